@@ -0,0 +1,51 @@
+package capi
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/giantswarm/microerror"
+)
+
+// ssmCommands install and start the SSM agent, so that the machines can be
+// accessed with AWS Session Manager. The CAPA images are based on Ubuntu,
+// which ships the agent as snap.
+var ssmCommands = []string{
+	"snap list amazon-ssm-agent || snap install amazon-ssm-agent --classic # install the SSM agent for Session Manager access",
+	"systemctl enable --now snap.amazon-ssm-agent.amazon-ssm-agent.service",
+}
+
+// access is how operators access the machines of the cluster.
+type access struct {
+	// sshKeyName is the EC2 key pair of the machines. nil uses the default
+	// key pair of CAPA, an empty string no key pair at all.
+	sshKeyName *string
+	// ssm installs the SSM agent on all machines.
+	ssm bool
+}
+
+func newAccess(config Config) (access, error) {
+	if config.SSHKeyName != "" && config.NoSSHKey {
+		return access{}, microerror.Maskf(invalidConfigError, "an SSH key name must not be given without SSH keys")
+	}
+
+	a := access{
+		ssm: config.SSM,
+	}
+	switch {
+	case config.NoSSHKey:
+		a.sshKeyName = aws.String("")
+	case config.SSHKeyName != "":
+		a.sshKeyName = aws.String(config.SSHKeyName)
+	}
+
+	return a, nil
+}
+
+// preKubeadmCommands returns the given commands of a machine preceded by the
+// setup of the access.
+func (a access) preKubeadmCommands(commands []string) []string {
+	if !a.ssm {
+		return commands
+	}
+
+	return append(append([]string{}, ssmCommands...), commands...)
+}
@@ -5,9 +5,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/ec2"
 	giantswarmawsalpha3 "github.com/giantswarm/apiextensions/pkg/apis/infrastructure/v1alpha2"
-	"github.com/giantswarm/microerror"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	capiawsv1alpha3 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
 	capiawsexpv1alpha3 "sigs.k8s.io/cluster-api-provider-aws/exp/api/v1alpha3"
@@ -15,51 +13,11 @@ import (
 	kubeadmapiv1alpha3 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1alpha3"
 	kubeadmtypev1beta1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/types/v1beta1"
 	expapiv1alpha3 "sigs.k8s.io/cluster-api/exp/api/v1alpha3"
-)
-
-func awsmachinepool(d *giantswarmawsalpha3.AWSMachineDeployment, region string, clusterID string) (*capiawsexpv1alpha3.AWSMachinePool, error) {
-	sess, err := getAWSSession(region)
-	if err != nil {
-		return nil, microerror.Mask(err)
-	}
-
-	ec2Client := ec2.New(sess)
-
-	i := &ec2.DescribeSecurityGroupsInput{
-		Filters: []*ec2.Filter{
-			{
-				Name:   aws.String("tag:Name"),
-				Values: aws.StringSlice([]string{fmt.Sprintf("%s-worker", clusterID)}),
-			},
-			{
-				Name:   aws.String("tag:giantswarm.io/machine-deployment"),
-				Values: aws.StringSlice([]string{d.Name}),
-			},
-		},
-	}
 
-	o, err := ec2Client.DescribeSecurityGroups(i)
-	if err != nil {
-		return nil, microerror.Mask(err)
-	}
-	if len(o.SecurityGroups) != 1 {
-		return nil, microerror.Maskf(nil, "expected 1 master security group but found %d", len(o.SecurityGroups))
-	}
-
-	i2 := &ec2.DescribeSubnetsInput{
-		Filters: []*ec2.Filter{
-			{
-				Name:   aws.String("tag:giantswarm.io/machine-deployment"),
-				Values: aws.StringSlice([]string{d.Name}),
-			},
-		},
-	}
-
-	o2, err := ec2Client.DescribeSubnets(i2)
-	if err != nil {
-		return nil, microerror.Mask(err)
-	}
+	"github.com/giantswarm/aws-gs-to-capi/giantswarm"
+)
 
+func awsmachinepool(d *giantswarmawsalpha3.AWSMachineDeployment, network giantswarm.NodePoolNetwork, access access, instanceProfile string, clusterID string, namespace string) *capiawsexpv1alpha3.AWSMachinePool {
 	awsmp := &capiawsexpv1alpha3.AWSMachinePool{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "AWSMachinePool",
@@ -67,7 +25,7 @@ func awsmachinepool(d *giantswarmawsalpha3.AWSMachineDeployment, region string,
 		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      machinePoolName(clusterID, d.Name),
-			Namespace: d.Namespace,
+			Namespace: namespace,
 		},
 		Spec: capiawsexpv1alpha3.AWSMachinePoolSpec{
 			MinSize: int32(d.Spec.NodePool.Scaling.Min),
@@ -75,35 +33,35 @@ func awsmachinepool(d *giantswarmawsalpha3.AWSMachineDeployment, region string,
 			AWSLaunchTemplate: capiawsexpv1alpha3.AWSLaunchTemplate{
 				Name:               d.Name,
 				InstanceType:       d.Spec.Provider.Worker.InstanceType,
-				SSHKeyName:         aws.String("vaclav"),
-				IamInstanceProfile: "nodes.cluster-api-provider-aws.sigs.k8s.io",
+				SSHKeyName:         access.sshKeyName,
+				IamInstanceProfile: instanceProfile,
 				AdditionalSecurityGroups: []capiawsv1alpha3.AWSResourceReference{
 					{
-						ID: o.SecurityGroups[0].GroupId,
+						ID: aws.String(network.SecurityGroupID),
 					},
 				},
 			},
 		},
 	}
 
-	for _, subnet := range o2.Subnets {
-		awsmp.Spec.Subnets = append(awsmp.Spec.Subnets, capiawsv1alpha3.AWSResourceReference{ID: subnet.SubnetId})
-		awsmp.Spec.AvailabilityZones = append(awsmp.Spec.AvailabilityZones, *subnet.AvailabilityZone)
+	for _, subnet := range network.Subnets {
+		awsmp.Spec.Subnets = append(awsmp.Spec.Subnets, capiawsv1alpha3.AWSResourceReference{ID: aws.String(subnet.ID)})
+		awsmp.Spec.AvailabilityZones = append(awsmp.Spec.AvailabilityZones, subnet.AvailabilityZone)
 	}
 
-	return awsmp, nil
+	return awsmp
 }
 
-func machinePool(d *giantswarmawsalpha3.AWSMachineDeployment, clusterID string, k8sVersion string) *expapiv1alpha3.MachinePool {
+func machinePool(d *giantswarmawsalpha3.AWSMachineDeployment, clusterID string, k8sVersion string, namespace string) *expapiv1alpha3.MachinePool {
 	replicas := int32(d.Spec.NodePool.Scaling.Min)
 	mp := &expapiv1alpha3.MachinePool{
 		TypeMeta: metav1.TypeMeta{
-			Kind:       "KubeadmConfig",
-			APIVersion: capiawsexpv1alpha3.GroupVersion.String(),
+			Kind:       "MachinePool",
+			APIVersion: expapiv1alpha3.GroupVersion.String(),
 		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      machinePoolName(clusterID, d.Name),
-			Namespace: d.Namespace,
+			Namespace: namespace,
 		},
 		Spec: expapiv1alpha3.MachinePoolSpec{
 			ClusterName: clusterID,
@@ -114,14 +72,14 @@ func machinePool(d *giantswarmawsalpha3.AWSMachineDeployment, clusterID string,
 					Version:     &k8sVersion,
 					InfrastructureRef: v1.ObjectReference{
 						Name:       machinePoolName(clusterID, d.Name),
-						Namespace:  d.Namespace,
+						Namespace:  namespace,
 						Kind:       "AWSMachinePool",
 						APIVersion: capiawsexpv1alpha3.GroupVersion.String(),
 					},
 					Bootstrap: apiv1alpha3.Bootstrap{
 						ConfigRef: &v1.ObjectReference{
 							Name:       machinePoolName(clusterID, d.Name),
-							Namespace:  d.Namespace,
+							Namespace:  namespace,
 							Kind:       "KubeadmConfig",
 							APIVersion: kubeadmapiv1alpha3.GroupVersion.String(),
 						},
@@ -133,63 +91,71 @@ func machinePool(d *giantswarmawsalpha3.AWSMachineDeployment, clusterID string,
 	return mp
 }
 
-func machinePoolKubeAdmConfig(d *giantswarmawsalpha3.AWSMachineDeployment, clusterID string) *kubeadmapiv1alpha3.KubeadmConfig {
+func machinePoolKubeAdmConfig(d *giantswarmawsalpha3.AWSMachineDeployment, access access, registration nodeRegistration, clusterID string, namespace string) *kubeadmapiv1alpha3.KubeadmConfig {
 	c := &kubeadmapiv1alpha3.KubeadmConfig{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "KubeadmConfig",
-			APIVersion: capiawsexpv1alpha3.GroupVersion.String(),
+			APIVersion: kubeadmapiv1alpha3.GroupVersion.String(),
 		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      machinePoolName(clusterID, d.Name),
-			Namespace: d.Namespace,
+			Namespace: namespace,
 		},
-		Spec: kubeadmapiv1alpha3.KubeadmConfigSpec{
-			PreKubeadmCommands: []string{
-				"hostnamectl set-hostname $(curl http://169.254.169.254/latest/meta-data/local-hostname)",
-			},
-			InitConfiguration: &kubeadmtypev1beta1.InitConfiguration{
-				NodeRegistration: kubeadmtypev1beta1.NodeRegistrationOptions{
-					KubeletExtraArgs: map[string]string{
-						"cloud-provider": "aws",
-					},
-					Name: "{{ ds.meta_data.local_hostname }}",
+		Spec: nodePoolKubeadmConfigSpec(access, registration, clusterID),
+	}
+
+	return c
+}
+
+// nodePoolKubeadmConfigSpec returns the bootstrap configuration of the workers
+// of a node pool, shared by MachinePools and MachineDeployments. The nodes
+// register with the labels and taints of the node pool.
+func nodePoolKubeadmConfigSpec(access access, registration nodeRegistration, clusterID string) kubeadmapiv1alpha3.KubeadmConfigSpec {
+	return kubeadmapiv1alpha3.KubeadmConfigSpec{
+		PreKubeadmCommands: access.preKubeadmCommands([]string{
+			"hostnamectl set-hostname $(curl http://169.254.169.254/latest/meta-data/local-hostname)",
+		}),
+		InitConfiguration: &kubeadmtypev1beta1.InitConfiguration{
+			NodeRegistration: kubeadmtypev1beta1.NodeRegistrationOptions{
+				KubeletExtraArgs: map[string]string{
+					"cloud-provider": "aws",
 				},
+				Name: "{{ ds.meta_data.local_hostname }}",
 			},
-			JoinConfiguration: &kubeadmtypev1beta1.JoinConfiguration{
-				NodeRegistration: kubeadmtypev1beta1.NodeRegistrationOptions{
-					KubeletExtraArgs: map[string]string{
-						"cloud-provider": "aws",
-						"node-labels":    "node.kubernetes.io/worker,role=worker",
-					},
-					Name: "{{ ds.meta_data.local_hostname }}",
+		},
+		JoinConfiguration: &kubeadmtypev1beta1.JoinConfiguration{
+			NodeRegistration: kubeadmtypev1beta1.NodeRegistrationOptions{
+				KubeletExtraArgs: map[string]string{
+					"cloud-provider": "aws",
+					"node-labels":    registration.nodeLabels(),
 				},
+				Name:   "{{ ds.meta_data.local_hostname }}",
+				Taints: registration.taints,
 			},
-			Files: []kubeadmapiv1alpha3.File{
-				{
-					Path:  "/etc/kubernetes/config/kube-proxy.yaml",
-					Owner: "root:root",
-					ContentFrom: &kubeadmapiv1alpha3.FileSource{
-						Secret: kubeadmapiv1alpha3.SecretFileSource{
-							Name: customFilesSecretName(clusterID),
-							Key:  kubeProxyKubeconfigKey,
-						},
+		},
+		Files: []kubeadmapiv1alpha3.File{
+			{
+				Path:  "/etc/kubernetes/config/kube-proxy.yaml",
+				Owner: "root:root",
+				ContentFrom: &kubeadmapiv1alpha3.FileSource{
+					Secret: kubeadmapiv1alpha3.SecretFileSource{
+						Name: customFilesSecretName(clusterID),
+						Key:  kubeProxyKubeconfigKey,
 					},
 				},
-				{
-					Path:  "/etc/kubernetes/config/proxy-config.yml",
-					Owner: "root:root",
-					ContentFrom: &kubeadmapiv1alpha3.FileSource{
-						Secret: kubeadmapiv1alpha3.SecretFileSource{
-							Name: customFilesSecretName(clusterID),
-							Key:  kubeProxyConfigKey,
-						},
+			},
+			{
+				Path:  "/etc/kubernetes/config/proxy-config.yml",
+				Owner: "root:root",
+				ContentFrom: &kubeadmapiv1alpha3.FileSource{
+					Secret: kubeadmapiv1alpha3.SecretFileSource{
+						Name: customFilesSecretName(clusterID),
+						Key:  kubeProxyConfigKey,
 					},
 				},
 			},
 		},
 	}
-
-	return c
 }
 
 func machinePoolName(clusterID string, machinePool string) string {
@@ -4,21 +4,45 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
-	v1alpha32 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1alpha3"
-	"sigs.k8s.io/cluster-api/exp/api/v1alpha3"
+	"sort"
 
-	"github.com/giantswarm/aws-gs-to-capi/vault"
 	"github.com/giantswarm/microerror"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	awsv1alpha3 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
 	capiawsexpv1alpha3 "sigs.k8s.io/cluster-api-provider-aws/exp/api/v1alpha3"
 	apiv1alpha3 "sigs.k8s.io/cluster-api/api/v1alpha3"
+	v1alpha32 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1alpha3"
 	kubeadmv1alpha3 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/exp/api/v1alpha3"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/giantswarm/aws-gs-to-capi/ctrlclient"
 	"github.com/giantswarm/aws-gs-to-capi/giantswarm"
 )
 
+const (
+	// fieldManager is the field manager of all applied objects.
+	fieldManager = "aws-gs-to-capi"
+
+	// DefaultControlPlaneInstanceProfile and DefaultNodeInstanceProfile are
+	// the IAM instance profiles created by clusterawsadm.
+	DefaultControlPlaneInstanceProfile = "control-plane.cluster-api-provider-aws.sigs.k8s.io"
+	DefaultNodeInstanceProfile         = "nodes.cluster-api-provider-aws.sigs.k8s.io"
+)
+
+type ApplyResult string
+
+const (
+	ApplyResultCreated   ApplyResult = "created"
+	ApplyResultUpdated   ApplyResult = "updated"
+	ApplyResultUnchanged ApplyResult = "unchanged"
+)
+
 type Crs struct {
 	CustomFiles *v1.Secret
 	EtcdCerts   *v1.Secret
@@ -32,66 +56,240 @@ type Crs struct {
 	OldControlPlaneMachines     []apiv1alpha3.Machine
 
 	MachinePools []*MachinePoolSpec
+
+	// Topology is the Cluster with spec.topology when the cluster is created
+	// from a ClusterClass. The Cluster, AWSCluster, control plane and node
+	// pool objects above are then only the internal representation it was
+	// built from and are not created.
+	Topology *unstructured.Unstructured
+
+	// Versions are the component versions the CRs were generated with.
+	Versions Versions
+
+	// Warnings name the settings of the GS cluster which have no equivalent
+	// in the CRs.
+	Warnings []string
+
+	// writer converts the objects into the CAPI API version of the CAPI
+	// management cluster.
+	writer Writer
 }
 
 type MachinePoolSpec struct {
+	// NodePoolID is the ID of the GS node pool, i.e. the name of the
+	// AWSMachineDeployment the machine pool was created from.
+	NodePoolID string
+	// Mode is the way the node pool is created, one of NodePoolModes. Only
+	// the objects of the mode are set.
+	Mode string
+
 	AWSMachinePool *capiawsexpv1alpha3.AWSMachinePool
 	MachinePool    *v1alpha3.MachinePool
 	KubeadmConfig  *v1alpha32.KubeadmConfig
+
+	AWSMachineTemplates   []*awsv1alpha3.AWSMachineTemplate
+	KubeadmConfigTemplate *v1alpha32.KubeadmConfigTemplate
+	MachineDeployments    []*apiv1alpha3.MachineDeployment
+}
+
+type Config struct {
+	// K8sVersion is the Kubernetes version of the new CAPI cluster. Defaults
+	// to the version of the GS release of the cluster.
+	K8sVersion string
+	// Namespace is the namespace of the CAPI objects on the CAPI management
+	// cluster. Defaults to the namespace of the GS CRs.
+	Namespace string
+	// APIVersion is the CAPI API version the objects are written in.
+	// Defaults to v1alpha3.
+	APIVersion string
+	// NodePoolMode is the way node pools are created, one of NodePoolModes.
+	// Defaults to machinepool.
+	NodePoolMode string
+	// NodePoolModes overrides NodePoolMode for single node pools, keyed by
+	// node pool ID.
+	NodePoolModes map[string]string
+	// ClusterClass is the name of the ClusterClass to create the cluster
+	// from. If set, a Cluster with spec.topology is created instead of the
+	// Cluster, AWSCluster, control plane and node pool objects. It requires
+	// the v1beta1 API version and the machinedeployment node pool mode.
+	ClusterClass string
+	// WorkerClass is the MachineDeployment class of the ClusterClass the node
+	// pools are created with. Defaults to default-worker.
+	WorkerClass string
+	// SSHKeyName is the EC2 key pair of all machines. Defaults to the default
+	// key pair of CAPA.
+	SSHKeyName string
+	// NoSSHKey creates the machines without EC2 key pair.
+	NoSSHKey bool
+	// SSM installs the SSM agent on all machines, so that they can be accessed
+	// with AWS Session Manager.
+	SSM bool
+	// ControlPlaneInstanceProfile is the IAM instance profile of the control
+	// plane machines. Defaults to DefaultControlPlaneInstanceProfile.
+	ControlPlaneInstanceProfile string
+	// NodeInstanceProfile is the IAM instance profile of the workers.
+	// Defaults to DefaultNodeInstanceProfile.
+	NodeInstanceProfile string
+	// NodePoolInstanceProfiles overrides NodeInstanceProfile for single node
+	// pools, keyed by node pool ID.
+	NodePoolInstanceProfiles map[string]string
+	// LabelPrefixes are the key prefixes of the labels of the GS CRs which are
+	// copied to the CAPI objects. nil uses DefaultLabelPrefixes, an empty
+	// slice copies no labels.
+	LabelPrefixes []string
+	// AnnotationPrefixes are the key prefixes of the annotations of the GS
+	// CRs which are copied to the CAPI objects. The descriptions of the
+	// cluster and node pools are always copied.
+	AnnotationPrefixes []string
+	// NodePoolLabels are node labels added to the ones of the GS node pools,
+	// keyed by node pool ID.
+	NodePoolLabels map[string]map[string]string
+	// NodePoolTaints are node taints added to the ones of the GS node pools,
+	// keyed by node pool ID.
+	NodePoolTaints map[string][]v1.Taint
+}
+
+// checkNodePoolIDs checks that the settings of single node pools refer to node
+// pools of the GS cluster, a typo would otherwise silently drop them.
+func checkNodePoolIDs(gsCRs *giantswarm.GSClusterCrs, config Config) error {
+	known := map[string]bool{}
+	for _, md := range gsCRs.AWSMachineDeployments {
+		known[md.Name] = true
+	}
+
+	ids := map[string]string{}
+	for id := range config.NodePoolModes {
+		ids[id] = "node pool mode"
+	}
+	for id := range config.NodePoolInstanceProfiles {
+		ids[id] = "instance profile"
+	}
+	for id := range config.NodePoolLabels {
+		ids[id] = "node labels"
+	}
+	for id := range config.NodePoolTaints {
+		ids[id] = "node taints"
+	}
+
+	var unknown []string
+	for id := range ids {
+		if !known[id] {
+			unknown = append(unknown, id)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return microerror.Maskf(invalidConfigError, "%s given for node pool %s, which is not a node pool of cluster %s", ids[unknown[0]], unknown[0], gsCRs.AWSCluster.Name)
+	}
+
+	return nil
 }
 
-func TransformGsToCAPICrs(gsCRs *giantswarm.GSClusterCrs, k8sVersion string) (*Crs, error) {
+func TransformGsToCAPICrs(gsCRs *giantswarm.GSClusterCrs, config Config) (*Crs, error) {
 	var err error
 	clusterID := gsCRs.AWSCluster.Name
-	namespace := gsCRs.AWSCluster.Namespace
+
+	versions, err := ResolveVersions(gsCRs.Release, config.K8sVersion)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	apiVersion := config.APIVersion
+	if apiVersion == "" {
+		apiVersion = APIVersionV1alpha3
+	}
+	writer, err := NewWriter(apiVersion)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	for id, mode := range config.NodePoolModes {
+		if !isNodePoolMode(mode) {
+			return nil, microerror.Maskf(invalidConfigError, "node pool mode of node pool %s must be one of %v but got %q", id, NodePoolModes, mode)
+		}
+	}
+	defaultMode := config.NodePoolMode
+	if defaultMode == "" {
+		defaultMode = NodePoolModeMachinePool
+		if config.ClusterClass != "" {
+			defaultMode = NodePoolModeMachineDeployment
+		}
+	}
+	if config.ClusterClass != "" && apiVersion != APIVersionV1beta1 {
+		return nil, microerror.Maskf(invalidConfigError, "a ClusterClass requires the CAPI API version %s but got %q", APIVersionV1beta1, apiVersion)
+	}
+	if !isNodePoolMode(defaultMode) {
+		return nil, microerror.Maskf(invalidConfigError, "node pool mode must be one of %v but got %q", NodePoolModes, defaultMode)
+	}
+
+	err = checkNodePoolIDs(gsCRs, config)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	access, err := newAccess(config)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	namespace := config.Namespace
+	if namespace == "" {
+		namespace = gsCRs.Namespace
+	}
 
 	p := CustomFilesParams{
-		APIEndpoint:   apiEndpointFromDomain(gsCRs.AWSCluster.Spec.Cluster.DNS.Domain, clusterID),
-		ClusterID:     clusterID,
-		ETCDEndpoint:  etcdEndpointFromDomain(gsCRs.AWSCluster.Spec.Cluster.DNS.Domain, clusterID),
-		EncryptionKey: string(gsCRs.EncryptionKey.Data["encryption"]),
-		Namespace:     namespace,
-		KubeProxyCA:   base64.StdEncoding.EncodeToString(gsCRs.KubeproxyCerts.Data["ca"]),
-		KubeProxyKey:  base64.StdEncoding.EncodeToString(gsCRs.KubeproxyCerts.Data["key"]),
-		KubeProxyCrt:  base64.StdEncoding.EncodeToString(gsCRs.KubeproxyCerts.Data["crt"]),
+		APIEndpoint:    apiEndpointFromDomain(gsCRs.AWSCluster.Spec.Cluster.DNS.Domain, clusterID),
+		ClusterID:      clusterID,
+		ETCDEndpoint:   etcdEndpointFromDomain(gsCRs.AWSCluster.Spec.Cluster.DNS.Domain, clusterID),
+		EncryptionKey:  string(gsCRs.EncryptionKey.Data["encryption"]),
+		Namespace:      namespace,
+		KubeProxyCA:    base64.StdEncoding.EncodeToString(gsCRs.KubeproxyCerts.Data["ca"]),
+		KubeProxyKey:   base64.StdEncoding.EncodeToString(gsCRs.KubeproxyCerts.Data["key"]),
+		KubeProxyCrt:   base64.StdEncoding.EncodeToString(gsCRs.KubeproxyCerts.Data["crt"]),
+		EtcdctlVersion: versions.Etcdctl,
 	}
 
 	secret, err := customFilesSecret(p)
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
-	cluster := transformCluster(gsCRs)
-
-	awsCluster, err := transformAWSCluster(gsCRs.AWSCluster)
+	cluster, err := transformCluster(gsCRs, namespace)
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
 
-	kubeadmCP := transformKubeAdmControlPlane(gsCRs, k8sVersion)
+	awsCluster, err := transformAWSCluster(gsCRs.AWSCluster, gsCRs.AWSControlPlane, gsCRs.Network, namespace)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
 
-	cpMachineTemplate, err := transformAWSMachineTemplateCP(gsCRs.AWSControlPlane, clusterID, gsCRs.AWSCluster.Spec.Provider.Region)
+	kubeadmCP, err := transformKubeAdmControlPlane(gsCRs, versions, access, namespace)
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
 
-	caPrivKey, err := vault.GetVaultCAKey(clusterID)
+	cpInstanceProfile := config.ControlPlaneInstanceProfile
+	if cpInstanceProfile == "" {
+		cpInstanceProfile = DefaultControlPlaneInstanceProfile
+	}
+	cpMachineTemplate := transformAWSMachineTemplateCP(gsCRs.AWSControlPlane, gsCRs.Network, access, cpInstanceProfile, clusterID, namespace)
+
+	labelPrefixes := config.LabelPrefixes
+	if labelPrefixes == nil {
+		labelPrefixes = DefaultLabelPrefixes
+	}
+	clusterMetadata := newClusterMetadata(gsCRs, labelPrefixes, config.AnnotationPrefixes)
+	awsCluster.Spec.AdditionalTags = clusterMetadata.tags
+	err = clusterMetadata.apply(cluster, awsCluster, kubeadmCP, cpMachineTemplate)
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
 
-	gsCRs.EtcdCerts.Name = etcdCertsName(clusterID)
-	gsCRs.EtcdCerts.APIVersion = secret.APIVersion
-	gsCRs.EtcdCerts.Kind = secret.Kind
-	gsCRs.EtcdCerts.ResourceVersion = ""
-	gsCRs.EtcdCerts.UID = ""
+	sanitizeSecret(gsCRs.EtcdCerts, etcdCertsName(clusterID), namespace)
 	gsCRs.EtcdCerts.Data["tls.crt"] = gsCRs.EtcdCerts.Data["ca"]
-	gsCRs.EtcdCerts.Data["tls.key"] = []byte(caPrivKey)
+	gsCRs.EtcdCerts.Data["tls.key"] = []byte(gsCRs.VaultCAKey)
 
-	gsCRs.SACerts.Name = saCertsName(clusterID)
-	gsCRs.SACerts.APIVersion = secret.APIVersion
-	gsCRs.SACerts.Kind = secret.Kind
-	gsCRs.SACerts.ResourceVersion = ""
-	gsCRs.SACerts.UID = ""
+	sanitizeSecret(gsCRs.SACerts, saCertsName(clusterID), namespace)
 	gsCRs.SACerts.Data["tls.crt"] = gsCRs.SACerts.Data["crt"]
 	gsCRs.SACerts.Data["tls.key"] = gsCRs.SACerts.Data["key"]
 
@@ -108,151 +306,385 @@ func TransformGsToCAPICrs(gsCRs *giantswarm.GSClusterCrs, k8sVersion string) (*C
 		AWSCluster:                  awsCluster,
 		ControlPlane:                kubeadmCP,
 		ControlPlaneMachineTemplate: cpMachineTemplate,
+
+		Versions: versions,
+
+		writer: writer,
 	}
 
 	for _, md := range gsCRs.AWSMachineDeployments {
-		awsmp, err := awsmachinepool(md, gsCRs.AWSCluster.Spec.Provider.Region, clusterID)
+		network, ok := gsCRs.Network.NodePools[md.Name]
+		if !ok {
+			return nil, microerror.Maskf(executionFailedError, "network of node pool %s not found", md.Name)
+		}
+
+		mode, ok := config.NodePoolModes[md.Name]
+		if !ok {
+			mode = defaultMode
+		}
+		instanceProfile, ok := config.NodePoolInstanceProfiles[md.Name]
+		if !ok {
+			instanceProfile = config.NodeInstanceProfile
+		}
+		if instanceProfile == "" {
+			instanceProfile = DefaultNodeInstanceProfile
+		}
+
+		registration, warnings, err := newNodeRegistration(md, gsCRs.NodePoolNodes[md.Name], config.NodePoolLabels[md.Name], config.NodePoolTaints[md.Name])
 		if err != nil {
 			return nil, microerror.Mask(err)
 		}
+		crs.Warnings = append(crs.Warnings, warnings...)
 
-		mp := machinePool(md, clusterID, k8sVersion)
-		kubeadmConfig := machinePoolKubeAdmConfig(md, clusterID)
+		spec := &MachinePoolSpec{
+			NodePoolID: md.Name,
+			Mode:       mode,
+		}
+		switch mode {
+		case NodePoolModeMachineDeployment:
+			spec.MachineDeployments, spec.AWSMachineTemplates = machineDeployments(md, network, access, instanceProfile, clusterID, versions.Kubernetes, namespace)
+			spec.KubeadmConfigTemplate = machineDeploymentKubeadmConfigTemplate(md, access, registration, clusterID, namespace)
+
+			spot, warnings := spotMarketOptions(md)
+			for _, t := range spec.AWSMachineTemplates {
+				t.Spec.Template.Spec.SpotMarketOptions = spot.DeepCopy()
+			}
+			crs.Warnings = append(crs.Warnings, warnings...)
+		default:
+			spec.AWSMachinePool = awsmachinepool(md, network, access, instanceProfile, clusterID, namespace)
+			spec.MachinePool = machinePool(md, clusterID, versions.Kubernetes, namespace)
+			spec.KubeadmConfig = machinePoolKubeAdmConfig(md, access, registration, clusterID, namespace)
+
+			var warnings []string
+			spec.AWSMachinePool.Spec.MixedInstancesPolicy, warnings = mixedInstancesPolicy(md)
+			crs.Warnings = append(crs.Warnings, warnings...)
+		}
 
-		crs.MachinePools = append(crs.MachinePools, &MachinePoolSpec{
-			AWSMachinePool: awsmp,
-			MachinePool:    mp,
-			KubeadmConfig:  kubeadmConfig,
-		})
+		npMetadata := clusterMetadata.nodePool(md, network, labelPrefixes, config.AnnotationPrefixes)
+		if spec.AWSMachinePool != nil {
+			spec.AWSMachinePool.Spec.AdditionalTags = npMetadata.tags
+		}
+		for _, t := range spec.AWSMachineTemplates {
+			t.Spec.Template.Spec.AdditionalTags = npMetadata.tags
+		}
+		err = npMetadata.apply(spec.Objects()...)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		crs.MachinePools = append(crs.MachinePools, spec)
+	}
+
+	if config.ClusterClass != "" {
+		workerClass := config.WorkerClass
+		if workerClass == "" {
+			workerClass = defaultWorkerClass
+		}
+
+		crs.Topology, err = topologyCluster(crs, access, config.ClusterClass, workerClass)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
 	}
 
 	return crs, nil
 }
 
-func CreateControlPlaneResources(crs *Crs, k8sContext string) error {
-	ctx := context.Background()
-	ctrl, err := ctrlclient.GetCtrlClient(k8sContext)
-	if err != nil {
-		return microerror.Mask(err)
+// ControlPlaneObjects returns the secrets and control plane resources in the
+// order in which they have to be created.
+func (crs *Crs) ControlPlaneObjects() []runtime.Object {
+	return append(crs.SecretObjects(), crs.ClusterObjects()...)
+}
+
+// SecretObjects returns the secrets referenced by the control plane and the
+// node pools.
+func (crs *Crs) SecretObjects() []runtime.Object {
+	return []runtime.Object{
+		crs.CustomFiles,
+		crs.EtcdCerts,
+		crs.SACerts,
+		crs.CACerts,
 	}
+}
 
-	err = ctrl.Create(ctx, crs.CustomFiles)
-	if err != nil {
-		return microerror.Mask(err)
+// ClusterObjects returns the control plane resources without the secrets.
+// With a topology this is the topology Cluster without workers.
+func (crs *Crs) ClusterObjects() []runtime.Object {
+	if crs.Topology != nil {
+		return []runtime.Object{withoutWorkers(crs.Topology)}
 	}
 
-	err = ctrl.Create(ctx, crs.EtcdCerts)
-	if err != nil {
-		return microerror.Mask(err)
+	return []runtime.Object{
+		crs.Cluster,
+		crs.AWSCluster,
+		crs.ControlPlane,
+		crs.ControlPlaneMachineTemplate,
+	}
+}
+
+// NodePoolObjects returns the resources of all node pools in the order in
+// which they have to be created. With a topology this is the topology Cluster
+// including the workers, since the node pools are part of it.
+func (crs *Crs) NodePoolObjects() []runtime.Object {
+	if crs.Topology != nil {
+		return []runtime.Object{crs.Topology}
 	}
-	err = ctrl.Create(ctx, crs.SACerts)
+
+	var objs []runtime.Object
+	for _, mp := range crs.MachinePools {
+		objs = append(objs, mp.Objects()...)
+	}
+
+	return objs
+}
+
+// Objects returns all resources of the cluster in the order in which they
+// have to be created.
+func (crs *Crs) Objects() []runtime.Object {
+	if crs.Topology != nil {
+		return append(crs.SecretObjects(), crs.Topology)
+	}
+
+	return append(crs.ControlPlaneObjects(), crs.NodePoolObjects()...)
+}
+
+// Objects returns the resources of the node pool in the order in which they
+// have to be created.
+func (mp *MachinePoolSpec) Objects() []runtime.Object {
+	if mp.Mode == NodePoolModeMachineDeployment {
+		var objs []runtime.Object
+		for _, t := range mp.AWSMachineTemplates {
+			objs = append(objs, t)
+		}
+		objs = append(objs, mp.KubeadmConfigTemplate)
+		for _, md := range mp.MachineDeployments {
+			objs = append(objs, md)
+		}
+
+		return objs
+	}
+
+	return []runtime.Object{
+		mp.AWSMachinePool,
+		mp.KubeadmConfig,
+		mp.MachinePool,
+	}
+}
+
+// ControlPlaneInstanceProfile returns the IAM instance profile of the control
+// plane machines.
+func (crs *Crs) ControlPlaneInstanceProfile() string {
+	return crs.ControlPlaneMachineTemplate.Spec.Template.Spec.IAMInstanceProfile
+}
+
+// InstanceProfile returns the IAM instance profile of the workers of the node
+// pool.
+func (mp *MachinePoolSpec) InstanceProfile() string {
+	if mp.Mode == NodePoolModeMachineDeployment {
+		// All templates of a node pool share the instance profile.
+		for _, t := range mp.AWSMachineTemplates {
+			return t.Spec.Template.Spec.IAMInstanceProfile
+		}
+		return ""
+	}
+
+	return mp.AWSMachinePool.Spec.AWSLaunchTemplate.IamInstanceProfile
+}
+
+// APIVersion returns the CAPI API version the objects are written in.
+func (crs *Crs) APIVersion() string {
+	return crs.writer.APIVersion()
+}
+
+// Write converts the given objects of the CRs into the CAPI API version of
+// the CRs. It has to be used for all objects leaving the tool, the objects
+// returned by the other methods are always v1alpha3.
+func (crs *Crs) Write(objs []runtime.Object) ([]runtime.Object, error) {
+	var written []runtime.Object
+	for _, o := range objs {
+		w, err := crs.writer.Write(o)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		written = append(written, w)
+	}
+
+	return written, nil
+}
+
+// ApplyControlPlaneResources applies the secrets and control plane resources.
+// A new control plane is created with a single replica, it is grown with
+// ScaleControlPlane.
+func ApplyControlPlaneResources(crs *Crs, k8sContext string) error {
+	applied, err := crs.Applied(k8sContext)
 	if err != nil {
 		return microerror.Mask(err)
 	}
-	err = ctrl.Create(ctx, crs.CACerts)
+
+	objs, err := crs.Write(applied.ControlPlaneObjects())
 	if err != nil {
 		return microerror.Mask(err)
 	}
 
-	err = ctrl.Create(ctx, crs.Cluster)
+	err = ApplyResources(objs, k8sContext)
 	if err != nil {
 		return microerror.Mask(err)
 	}
-	err = ctrl.Create(ctx, crs.AWSCluster)
+
+	return nil
+}
+
+// ApplyNodePoolResources applies the resources of all node pools.
+func ApplyNodePoolResources(crs *Crs, k8sContext string) error {
+	applied, err := crs.Applied(k8sContext)
 	if err != nil {
 		return microerror.Mask(err)
 	}
-	err = ctrl.Create(ctx, crs.ControlPlane)
+
+	objs, err := crs.Write(applied.NodePoolObjects())
 	if err != nil {
 		return microerror.Mask(err)
 	}
-	err = ctrl.Create(ctx, crs.ControlPlaneMachineTemplate)
+
+	err = ApplyResources(objs, k8sContext)
 	if err != nil {
 		return microerror.Mask(err)
 	}
+
 	return nil
 }
 
-func CreateNodePoolResources(crs *Crs, k8sContext string) error {
+// ApplyResources applies the objects in the given order using server-side
+// apply, so that applying them again converges to the desired objects instead
+// of failing for objects which already exist. The result is printed for every
+// object.
+func ApplyResources(objs []runtime.Object, k8sContext string) error {
 	ctx := context.Background()
 	ctrl, err := ctrlclient.GetCtrlClient(k8sContext)
 	if err != nil {
 		return microerror.Mask(err)
 	}
 
-	for _, mp := range crs.MachinePools {
-		err = ctrl.Create(ctx, mp.AWSMachinePool)
-		if err != nil {
-			return microerror.Mask(err)
-		}
-		err = ctrl.Create(ctx, mp.KubeadmConfig)
+	for _, o := range objs {
+		result, err := applyResource(ctx, ctrl, o)
 		if err != nil {
 			return microerror.Mask(err)
 		}
-		err = ctrl.Create(ctx, mp.MachinePool)
+
+		m, err := meta.Accessor(o)
 		if err != nil {
 			return microerror.Mask(err)
 		}
+		fmt.Printf("%s %s/%s %s\n", o.GetObjectKind().GroupVersionKind().Kind, m.GetNamespace(), m.GetName(), result)
 	}
 
 	return nil
 }
 
-func DeleteNPResources(crs *Crs, k8sContext string) error {
-	ctx := context.Background()
-	ctrl, err := ctrlclient.GetCtrlClient(k8sContext)
+func applyResource(ctx context.Context, ctrl client.Client, o runtime.Object) (ApplyResult, error) {
+	m, err := meta.Accessor(o)
 	if err != nil {
-		return microerror.Mask(err)
+		return "", microerror.Mask(err)
 	}
 
-	for _, mp := range crs.MachinePools {
-		err = ctrl.Delete(ctx, mp.AWSMachinePool)
-		err = ctrl.Delete(ctx, mp.KubeadmConfig)
-		err = ctrl.Delete(ctx, mp.MachinePool)
+	var resourceVersion string
+	{
+		current := &unstructured.Unstructured{}
+		current.SetGroupVersionKind(o.GetObjectKind().GroupVersionKind())
+
+		err = ctrl.Get(ctx, client.ObjectKey{Namespace: m.GetNamespace(), Name: m.GetName()}, current)
+		if err == nil {
+			resourceVersion = current.GetResourceVersion()
+		} else if !apierrors.IsNotFound(err) {
+			return "", microerror.Mask(err)
+		}
 	}
 
-	return nil
-}
+	// Server-side apply does not accept a resource version or managed fields
+	// in the applied configuration.
+	m.SetResourceVersion("")
+	m.SetManagedFields(nil)
 
-func DeleteCPResources(crs *Crs, k8sContext string) error {
-	ctx := context.Background()
-	ctrl, err := ctrlclient.GetCtrlClient(k8sContext)
+	err = ctrl.Patch(ctx, o, client.Apply, client.FieldOwner(fieldManager), client.ForceOwnership)
 	if err != nil {
-		return microerror.Mask(err)
+		return "", microerror.Mask(err)
 	}
 
-	err = ctrl.Delete(ctx, crs.CustomFiles)
-	if err != nil {
-		return microerror.Mask(err)
+	switch resourceVersion {
+	case "":
+		return ApplyResultCreated, nil
+	case m.GetResourceVersion():
+		return ApplyResultUnchanged, nil
+	default:
+		return ApplyResultUpdated, nil
 	}
-	err = ctrl.Delete(ctx, crs.EtcdCerts)
-	if err != nil {
-		return microerror.Mask(err)
+}
+
+func DeleteNPResources(crs *Crs, k8sContext string) error {
+	// The node pools of a topology are removed by applying the Cluster
+	// without workers, deleting the Cluster would delete the control plane.
+	if crs.Topology != nil {
+		applied, err := crs.Applied(k8sContext)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		objs, err := crs.Write(applied.ClusterObjects())
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		err = ApplyResources(objs, k8sContext)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		return nil
 	}
-	err = ctrl.Delete(ctx, crs.SACerts)
+
+	objs, err := crs.Write(crs.NodePoolObjects())
 	if err != nil {
 		return microerror.Mask(err)
 	}
-	err = ctrl.Delete(ctx, crs.CACerts)
+
+	err = deleteResources(objs, k8sContext, false)
 	if err != nil {
 		return microerror.Mask(err)
 	}
-	err = ctrl.Delete(ctx, crs.Cluster)
+
+	return nil
+}
+
+func DeleteCPResources(crs *Crs, k8sContext string) error {
+	objs, err := crs.Write(crs.ControlPlaneObjects())
 	if err != nil {
 		return microerror.Mask(err)
 	}
-	err = ctrl.Delete(ctx, crs.AWSCluster)
+
+	err = deleteResources(objs, k8sContext, true)
 	if err != nil {
 		return microerror.Mask(err)
 	}
-	err = ctrl.Delete(ctx, crs.ControlPlane)
+
+	return nil
+}
+
+// deleteResources deletes the objects in the given order. Errors are only
+// returned if failOnError is set.
+func deleteResources(objs []runtime.Object, k8sContext string, failOnError bool) error {
+	ctx := context.Background()
+	ctrl, err := ctrlclient.GetCtrlClient(k8sContext)
 	if err != nil {
 		return microerror.Mask(err)
 	}
-	err = ctrl.Delete(ctx, crs.ControlPlaneMachineTemplate)
-	if err != nil {
-		return microerror.Mask(err)
+
+	for _, o := range objs {
+		err = ctrl.Delete(ctx, o)
+		if err != nil && failOnError {
+			return microerror.Mask(err)
+		}
 	}
+
 	return nil
 }
 
@@ -262,3 +694,17 @@ func etcdEndpointFromDomain(domain string, clusterID string) string {
 func apiEndpointFromDomain(domain string, clusterID string) string {
 	return fmt.Sprintf("api.%s.k8s.%s", clusterID, domain)
 }
+
+// sanitizeSecret turns a secret fetched from the GS management cluster into
+// a secret which can be created on the CAPI management cluster.
+func sanitizeSecret(s *v1.Secret, name string, namespace string) {
+	s.TypeMeta = metav1.TypeMeta{
+		Kind:       "Secret",
+		APIVersion: v1.SchemeGroupVersion.String(),
+	}
+	s.ObjectMeta = metav1.ObjectMeta{
+		Name:      name,
+		Namespace: namespace,
+		Labels:    s.Labels,
+	}
+}
@@ -2,7 +2,10 @@ package capi
 
 import (
 	"fmt"
+	"net"
+
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/giantswarm/microerror"
 	v1 "k8s.io/api/core/v1"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -16,9 +19,30 @@ import (
 func clusterName(clusterID string) string {
 	return fmt.Sprintf("%s", clusterID)
 }
-func transformCluster(gsCRs *giantswarm.GSClusterCrs) *apiv1alpha3.Cluster {
+
+// transformCluster returns the Cluster with the network of the GS cluster.
+// The service CIDR and cluster domain have to be known, since services and
+// DNS of the workloads break when they change on migration.
+func transformCluster(gsCRs *giantswarm.GSClusterCrs, namespace string) (*apiv1alpha3.Cluster, error) {
 	clusterID := gsCRs.AWSCluster.Name
 
+	network := gsCRs.ClusterNetwork
+	if network == nil {
+		return nil, microerror.Maskf(invalidConfigError, "the service CIDR and cluster domain of %s are unknown, they have to be read from the workload cluster or given explicitly", clusterID)
+	}
+	err := network.Validate()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	err = checkDNSIP(network)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	err = checkPodsCIDR(gsCRs.AWSCluster.Spec.Provider.Pods.CIDRBlock, network.ServiceCIDR, gsCRs.AWSCluster.Status.Provider.Network.CIDR)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
 	cluster := &apiv1alpha3.Cluster{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Cluster",
@@ -27,7 +51,7 @@ func transformCluster(gsCRs *giantswarm.GSClusterCrs) *apiv1alpha3.Cluster {
 
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      clusterName(clusterID),
-			Namespace: gsCRs.AWSCluster.Namespace,
+			Namespace: namespace,
 		},
 		Spec: apiv1alpha3.ClusterSpec{
 			ClusterNetwork: &apiv1alpha3.ClusterNetwork{
@@ -35,9 +59,9 @@ func transformCluster(gsCRs *giantswarm.GSClusterCrs) *apiv1alpha3.Cluster {
 					CIDRBlocks: []string{gsCRs.AWSCluster.Spec.Provider.Pods.CIDRBlock},
 				},
 				Services: &apiv1alpha3.NetworkRanges{
-					CIDRBlocks: []string{"172.31.0.0/16"},
+					CIDRBlocks: []string{network.ServiceCIDR},
 				},
-				ServiceDomain: "cluster.local",
+				ServiceDomain: network.ClusterDomain,
 				APIServerPort: aws.Int32(443),
 			},
 			ControlPlaneRef: &v1.ObjectReference{
@@ -53,6 +77,61 @@ func transformCluster(gsCRs *giantswarm.GSClusterCrs) *apiv1alpha3.Cluster {
 		},
 	}
 
-	return cluster
+	return cluster, nil
+}
+
+// checkDNSIP checks that the DNS IP of the GS cluster is the one kubeadm
+// configures the kubelets with, which is the 10th address of the service
+// CIDR.
+func checkDNSIP(network *giantswarm.ClusterNetwork) error {
+	if network.DNSIP == "" {
+		return nil
+	}
+
+	_, cidr, err := net.ParseCIDR(network.ServiceCIDR)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	ip := make(net.IP, len(cidr.IP))
+	copy(ip, cidr.IP)
+	ip[len(ip)-1] += 10
+
+	if !ip.Equal(net.ParseIP(network.DNSIP)) {
+		return microerror.Maskf(invalidConfigError, "DNS IP %s of the cluster is not %s, which kubeadm configures for the service CIDR %s", network.DNSIP, ip, network.ServiceCIDR)
+	}
+
+	return nil
+}
+
+// checkPodsCIDR checks that the pod CIDR of the GS cluster is valid and does
+// not overlap with the service CIDR or the CIDR of the VPC, which is only
+// checked when it is known.
+func checkPodsCIDR(podsCIDR string, serviceCIDR string, vpcCIDR string) error {
+	_, pods, err := net.ParseCIDR(podsCIDR)
+	if err != nil {
+		return microerror.Maskf(invalidConfigError, "pod CIDR %q of the cluster is invalid: %s", podsCIDR, err)
+	}
+
+	ranges := []struct {
+		name string
+		cidr string
+	}{
+		{name: "service CIDR", cidr: serviceCIDR},
+		{name: "VPC CIDR", cidr: vpcCIDR},
+	}
+	for _, r := range ranges {
+		if r.cidr == "" {
+			continue
+		}
+		_, n, err := net.ParseCIDR(r.cidr)
+		if err != nil {
+			return microerror.Maskf(invalidConfigError, "%s %q of the cluster is invalid: %s", r.name, r.cidr, err)
+		}
+		if pods.Contains(n.IP) || n.Contains(pods.IP) {
+			return microerror.Maskf(invalidConfigError, "pod CIDR %s overlaps with the %s %s", podsCIDR, r.name, r.cidr)
+		}
+	}
 
+	return nil
 }
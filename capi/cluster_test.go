@@ -0,0 +1,76 @@
+package capi
+
+import (
+	"testing"
+)
+
+func Test_checkPodsCIDR(t *testing.T) {
+	testCases := []struct {
+		name        string
+		podsCIDR    string
+		serviceCIDR string
+		vpcCIDR     string
+		valid       bool
+	}{
+		{
+			name:        "case 0: separate ranges",
+			podsCIDR:    "10.2.0.0/16",
+			serviceCIDR: "172.31.0.0/16",
+			vpcCIDR:     "10.1.0.0/24",
+			valid:       true,
+		},
+		{
+			name:        "case 1: unknown VPC CIDR",
+			podsCIDR:    "10.2.0.0/16",
+			serviceCIDR: "172.31.0.0/16",
+			valid:       true,
+		},
+		{
+			name:        "case 2: no pod CIDR",
+			serviceCIDR: "172.31.0.0/16",
+			vpcCIDR:     "10.1.0.0/24",
+		},
+		{
+			name:        "case 3: invalid pod CIDR",
+			podsCIDR:    "10.2.0.0",
+			serviceCIDR: "172.31.0.0/16",
+			vpcCIDR:     "10.1.0.0/24",
+		},
+		{
+			name:        "case 4: service CIDR inside the pod CIDR",
+			podsCIDR:    "172.16.0.0/12",
+			serviceCIDR: "172.31.0.0/16",
+			vpcCIDR:     "10.1.0.0/24",
+		},
+		{
+			name:        "case 5: pod CIDR inside the service CIDR",
+			podsCIDR:    "172.31.128.0/17",
+			serviceCIDR: "172.31.0.0/16",
+			vpcCIDR:     "10.1.0.0/24",
+		},
+		{
+			name:        "case 6: pod CIDR overlaps with the VPC CIDR",
+			podsCIDR:    "10.0.0.0/8",
+			serviceCIDR: "172.31.0.0/16",
+			vpcCIDR:     "10.1.0.0/24",
+		},
+		{
+			name:        "case 7: invalid VPC CIDR",
+			podsCIDR:    "10.2.0.0/16",
+			serviceCIDR: "172.31.0.0/16",
+			vpcCIDR:     "vpc",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkPodsCIDR(tc.podsCIDR, tc.serviceCIDR, tc.vpcCIDR)
+			if tc.valid && err != nil {
+				t.Fatalf("checkPodsCIDR() error = %v", err)
+			}
+			if !tc.valid && !IsInvalidConfig(err) {
+				t.Fatalf("checkPodsCIDR() error = %v, want invalid config error", err)
+			}
+		})
+	}
+}
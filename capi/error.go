@@ -0,0 +1,30 @@
+package capi
+
+import "github.com/giantswarm/microerror"
+
+var invalidVersionError = &microerror.Error{
+	Kind: "invalidVersionError",
+}
+
+// IsInvalidVersion asserts invalidVersionError.
+func IsInvalidVersion(err error) bool {
+	return microerror.Cause(err) == invalidVersionError
+}
+
+var executionFailedError = &microerror.Error{
+	Kind: "executionFailedError",
+}
+
+// IsExecutionFailed asserts executionFailedError.
+func IsExecutionFailed(err error) bool {
+	return microerror.Cause(err) == executionFailedError
+}
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
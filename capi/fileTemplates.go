@@ -3,7 +3,7 @@ package capi
 const unitTmpl = `#!/bin/sh
 # get ETCDCTL
 DOWNLOAD_URL=https://github.com/etcd-io/etcd/releases/download
-ETCD_VER=v3.4.13
+ETCD_VER={{.EtcdctlVersion}}
 rm -f /tmp/etcd-${ETCD_VER}-linux-amd64.tar.gz
 rm -rf /tmp/etcd && mkdir -p /tmp/etcd
 curl -L ${DOWNLOAD_URL}/${ETCD_VER}/etcd-${ETCD_VER}-linux-amd64.tar.gz -o /tmp/etcd-${ETCD_VER}-linux-amd64.tar.gz
@@ -0,0 +1,98 @@
+package capi
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	giantswarmawsalpha3 "github.com/giantswarm/apiextensions/pkg/apis/infrastructure/v1alpha2"
+	capiawsv1alpha3 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	capiawsexpv1alpha3 "sigs.k8s.io/cluster-api-provider-aws/exp/api/v1alpha3"
+)
+
+// defaultOnDemandPercentage is the on-demand percentage above the base
+// capacity of GS node pools which do not set it.
+const defaultOnDemandPercentage = 100
+
+// mixedInstancesPolicy returns the mixed instances policy of the ASG of a
+// node pool, nil when the node pool only uses on-demand instances of a
+// single instance type. The returned warnings name the settings which are
+// not kept.
+func mixedInstancesPolicy(d *giantswarmawsalpha3.AWSMachineDeployment) (*capiawsexpv1alpha3.MixedInstancesPolicy, []string) {
+	var warnings []string
+
+	var overrides []capiawsexpv1alpha3.Overrides
+	if d.Spec.Provider.Worker.UseAlikeInstanceTypes {
+		types := instanceTypes(d)
+		if len(types) > 1 {
+			for _, t := range types {
+				overrides = append(overrides, capiawsexpv1alpha3.Overrides{InstanceType: t})
+			}
+		} else {
+			warnings = append(warnings, fmt.Sprintf("node pool %s uses alike instance types, but its status does not list them, only %s is used", d.Name, d.Spec.Provider.Worker.InstanceType))
+		}
+	}
+
+	percentage := onDemandPercentage(d)
+	if percentage == 100 && len(overrides) == 0 {
+		return nil, warnings
+	}
+
+	p := &capiawsexpv1alpha3.MixedInstancesPolicy{
+		InstancesDistribution: &capiawsexpv1alpha3.InstancesDistribution{
+			OnDemandAllocationStrategy:          capiawsexpv1alpha3.OnDemandAllocationStrategyPrioritized,
+			SpotAllocationStrategy:              capiawsexpv1alpha3.SpotAllocationStrategyLowestPrice,
+			OnDemandBaseCapacity:                aws.Int64(int64(d.Spec.Provider.InstanceDistribution.OnDemandBaseCapacity)),
+			OnDemandPercentageAboveBaseCapacity: aws.Int64(int64(percentage)),
+		},
+		Overrides: overrides,
+	}
+
+	return p, warnings
+}
+
+// spotMarketOptions returns the spot options of the machines of a node pool
+// created as MachineDeployments, which either use spot or on-demand
+// instances of a single instance type. Node pools mixing both get on-demand
+// instances only. The returned warnings name the settings which are not
+// kept.
+func spotMarketOptions(d *giantswarmawsalpha3.AWSMachineDeployment) (*capiawsv1alpha3.SpotMarketOptions, []string) {
+	var warnings []string
+
+	if d.Spec.Provider.Worker.UseAlikeInstanceTypes {
+		warnings = append(warnings, fmt.Sprintf("node pool %s uses alike instance types, which MachineDeployments do not support, only %s is used, use --node-pool-mode-override=%s=%s to keep them", d.Name, d.Spec.Provider.Worker.InstanceType, d.Name, NodePoolModeMachinePool))
+	}
+
+	base := d.Spec.Provider.InstanceDistribution.OnDemandBaseCapacity
+	percentage := onDemandPercentage(d)
+	switch {
+	case percentage == 100:
+		return nil, warnings
+	case percentage == 0 && base == 0:
+		return &capiawsv1alpha3.SpotMarketOptions{}, warnings
+	default:
+		warnings = append(warnings, fmt.Sprintf("node pool %s uses %d on-demand instances and %d%% on-demand instances above, which MachineDeployments do not support, all instances are on-demand, use --node-pool-mode-override=%s=%s to keep the spot instances", d.Name, base, percentage, d.Name, NodePoolModeMachinePool))
+		return nil, warnings
+	}
+}
+
+func onDemandPercentage(d *giantswarmawsalpha3.AWSMachineDeployment) int {
+	if p := d.Spec.Provider.InstanceDistribution.OnDemandPercentageAboveBaseCapacity; p != nil {
+		return *p
+	}
+
+	return defaultOnDemandPercentage
+}
+
+// instanceTypes returns the instance type of the node pool followed by the
+// alike instance types aws-operator chose for it, which are only known from
+// the status of the node pool.
+func instanceTypes(d *giantswarmawsalpha3.AWSMachineDeployment) []string {
+	types := []string{d.Spec.Provider.Worker.InstanceType}
+	for _, t := range d.Status.Provider.Worker.InstanceTypes {
+		if !contains(types, t) {
+			types = append(types, t)
+		}
+	}
+
+	return types
+}
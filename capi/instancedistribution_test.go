@@ -0,0 +1,183 @@
+package capi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	giantswarmawsalpha3 "github.com/giantswarm/apiextensions/pkg/apis/infrastructure/v1alpha2"
+	capiawsv1alpha3 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	capiawsexpv1alpha3 "sigs.k8s.io/cluster-api-provider-aws/exp/api/v1alpha3"
+)
+
+// newInstanceDistribution returns node pool np001 with instance type
+// m5.large. A nil percentage leaves the on-demand percentage unset.
+func newInstanceDistribution(base int, percentage *int, alike bool, statusTypes ...string) *giantswarmawsalpha3.AWSMachineDeployment {
+	md := newMachineDeployment("np001")
+	md.Spec.Provider.Worker.InstanceType = "m5.large"
+	md.Spec.Provider.Worker.UseAlikeInstanceTypes = alike
+	md.Spec.Provider.InstanceDistribution.OnDemandBaseCapacity = base
+	md.Spec.Provider.InstanceDistribution.OnDemandPercentageAboveBaseCapacity = percentage
+	md.Status.Provider.Worker.InstanceTypes = statusTypes
+
+	return md
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func Test_mixedInstancesPolicy(t *testing.T) {
+	testCases := []struct {
+		name             string
+		md               *giantswarmawsalpha3.AWSMachineDeployment
+		expected         *capiawsexpv1alpha3.MixedInstancesPolicy
+		expectedWarnings []string
+	}{
+		{
+			name: "case 0: on-demand percentage unset",
+			md:   newInstanceDistribution(0, nil, false),
+		},
+		{
+			name: "case 1: 100% on-demand without alike instance types",
+			md:   newInstanceDistribution(2, intPtr(100), false, "m5.large", "m4.large"),
+		},
+		{
+			name: "case 2: alike instance types",
+			md:   newInstanceDistribution(0, intPtr(100), true, "m4.large", "m5.large", "m5a.large"),
+			expected: &capiawsexpv1alpha3.MixedInstancesPolicy{
+				InstancesDistribution: &capiawsexpv1alpha3.InstancesDistribution{
+					OnDemandAllocationStrategy:          capiawsexpv1alpha3.OnDemandAllocationStrategyPrioritized,
+					SpotAllocationStrategy:              capiawsexpv1alpha3.SpotAllocationStrategyLowestPrice,
+					OnDemandBaseCapacity:                aws.Int64(0),
+					OnDemandPercentageAboveBaseCapacity: aws.Int64(100),
+				},
+				Overrides: []capiawsexpv1alpha3.Overrides{
+					{InstanceType: "m5.large"},
+					{InstanceType: "m4.large"},
+					{InstanceType: "m5a.large"},
+				},
+			},
+		},
+		{
+			name: "case 3: spot instances",
+			md:   newInstanceDistribution(1, intPtr(50), false),
+			expected: &capiawsexpv1alpha3.MixedInstancesPolicy{
+				InstancesDistribution: &capiawsexpv1alpha3.InstancesDistribution{
+					OnDemandAllocationStrategy:          capiawsexpv1alpha3.OnDemandAllocationStrategyPrioritized,
+					SpotAllocationStrategy:              capiawsexpv1alpha3.SpotAllocationStrategyLowestPrice,
+					OnDemandBaseCapacity:                aws.Int64(1),
+					OnDemandPercentageAboveBaseCapacity: aws.Int64(50),
+				},
+			},
+		},
+		{
+			name: "case 4: alike instance types not in the status",
+			md:   newInstanceDistribution(0, intPtr(0), true, "m5.large"),
+			expected: &capiawsexpv1alpha3.MixedInstancesPolicy{
+				InstancesDistribution: &capiawsexpv1alpha3.InstancesDistribution{
+					OnDemandAllocationStrategy:          capiawsexpv1alpha3.OnDemandAllocationStrategyPrioritized,
+					SpotAllocationStrategy:              capiawsexpv1alpha3.SpotAllocationStrategyLowestPrice,
+					OnDemandBaseCapacity:                aws.Int64(0),
+					OnDemandPercentageAboveBaseCapacity: aws.Int64(0),
+				},
+			},
+			expectedWarnings: []string{"does not list them"},
+		},
+		{
+			name:             "case 5: on-demand with alike instance types not in the status",
+			md:               newInstanceDistribution(0, nil, true),
+			expectedWarnings: []string{"does not list them"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, warnings := mixedInstancesPolicy(tc.md)
+			if !reflect.DeepEqual(p, tc.expected) {
+				t.Errorf("mixedInstancesPolicy() = %#v, want %#v", p, tc.expected)
+			}
+			checkWarnings(t, warnings, tc.expectedWarnings)
+		})
+	}
+}
+
+func Test_spotMarketOptions(t *testing.T) {
+	testCases := []struct {
+		name             string
+		md               *giantswarmawsalpha3.AWSMachineDeployment
+		expected         *capiawsv1alpha3.SpotMarketOptions
+		expectedWarnings []string
+	}{
+		{
+			name: "case 0: on-demand percentage unset",
+			md:   newInstanceDistribution(0, nil, false),
+		},
+		{
+			name: "case 1: 100% on-demand with base capacity",
+			md:   newInstanceDistribution(3, intPtr(100), false),
+		},
+		{
+			name:     "case 2: spot instances only",
+			md:       newInstanceDistribution(0, intPtr(0), false),
+			expected: &capiawsv1alpha3.SpotMarketOptions{},
+		},
+		{
+			name:             "case 3: spot instances above the base capacity",
+			md:               newInstanceDistribution(2, intPtr(0), false),
+			expectedWarnings: []string{"uses 2 on-demand instances and 0% on-demand instances above"},
+		},
+		{
+			name:             "case 4: mixed on-demand and spot instances",
+			md:               newInstanceDistribution(0, intPtr(50), false),
+			expectedWarnings: []string{"uses 0 on-demand instances and 50% on-demand instances above"},
+		},
+		{
+			name:             "case 5: alike instance types",
+			md:               newInstanceDistribution(0, nil, true, "m5.large", "m4.large"),
+			expectedWarnings: []string{"uses alike instance types, which MachineDeployments do not support"},
+		},
+		{
+			name:     "case 6: spot instances with alike instance types",
+			md:       newInstanceDistribution(0, intPtr(0), true, "m5.large", "m4.large"),
+			expected: &capiawsv1alpha3.SpotMarketOptions{},
+			expectedWarnings: []string{
+				"uses alike instance types, which MachineDeployments do not support",
+			},
+		},
+		{
+			name: "case 7: mixed instances with alike instance types",
+			md:   newInstanceDistribution(1, intPtr(25), true, "m5.large", "m4.large"),
+			expectedWarnings: []string{
+				"uses alike instance types, which MachineDeployments do not support",
+				"uses 1 on-demand instances and 25% on-demand instances above",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			o, warnings := spotMarketOptions(tc.md)
+			if !reflect.DeepEqual(o, tc.expected) {
+				t.Errorf("spotMarketOptions() = %#v, want %#v", o, tc.expected)
+			}
+			checkWarnings(t, warnings, tc.expectedWarnings)
+		})
+	}
+}
+
+// checkWarnings checks that every warning names node pool np001 and contains
+// the expected substring at the same position.
+func checkWarnings(t *testing.T, warnings []string, expected []string) {
+	t.Helper()
+
+	if len(warnings) != len(expected) {
+		t.Fatalf("warnings = %q, want %d warnings", warnings, len(expected))
+	}
+	for i, w := range warnings {
+		if !strings.Contains(w, "node pool np001") || !strings.Contains(w, expected[i]) {
+			t.Errorf("warning %q does not contain %q", w, expected[i])
+		}
+	}
+}
@@ -3,6 +3,8 @@ package capi
 import (
 	"fmt"
 
+	giantswarmawsalpha3 "github.com/giantswarm/apiextensions/pkg/apis/infrastructure/v1alpha2"
+	"github.com/giantswarm/microerror"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	capiawsv1alpha3 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
@@ -17,8 +19,29 @@ func kubeAdmControlPlaneName(clusterID string) string {
 	return fmt.Sprintf("%s-control-plane", clusterID)
 }
 
-func transformKubeAdmControlPlane(gsCRs *giantswarm.GSClusterCrs, k8sVersion string) *kubeadmv1alpha3.KubeadmControlPlane {
-	replicas := int32(1)
+// controlPlaneReplicas returns the number of masters of the GS cluster. The
+// replicas of older G8sControlPlanes are not set, they have a single master.
+func controlPlaneReplicas(cp *giantswarmawsalpha3.G8sControlPlane) (int32, error) {
+	replicas := int32(cp.Spec.Replicas)
+	if replicas == 0 {
+		replicas = 1
+	}
+	// etcd needs an odd number of members to tolerate failures.
+	if replicas < 0 || replicas%2 == 0 {
+		return 0, microerror.Maskf(invalidConfigError, "G8sControlPlane %s has %d replicas but an odd number is required", cp.Name, cp.Spec.Replicas)
+	}
+
+	return replicas, nil
+}
+
+// transformKubeAdmControlPlane returns the KubeadmControlPlane with all
+// replicas of the GS control plane. The machines are spread by CAPI across
+// the failure domains of the AWSCluster.
+func transformKubeAdmControlPlane(gsCRs *giantswarm.GSClusterCrs, versions Versions, access access, namespace string) (*kubeadmv1alpha3.KubeadmControlPlane, error) {
+	replicas, err := controlPlaneReplicas(gsCRs.G8sControlPlane)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
 	clusterID := gsCRs.AWSCluster.Name
 
 	cp := &kubeadmv1alpha3.KubeadmControlPlane{
@@ -28,7 +51,7 @@ func transformKubeAdmControlPlane(gsCRs *giantswarm.GSClusterCrs, k8sVersion str
 		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      kubeAdmControlPlaneName(gsCRs.AWSCluster.Name),
-			Namespace: gsCRs.AWSCluster.Namespace,
+			Namespace: namespace,
 		},
 		Spec: kubeadmv1alpha3.KubeadmControlPlaneSpec{
 			InfrastructureTemplate: v1.ObjectReference{
@@ -71,7 +94,7 @@ func transformKubeAdmControlPlane(gsCRs *giantswarm.GSClusterCrs, k8sVersion str
 								"experimental-peer-skip-client-san-verification": "true",
 							},
 							ImageMeta: kubeadmtypev1beta1.ImageMeta{
-								ImageTag:        "v3.4.14",
+								ImageTag:        versions.Etcd,
 								ImageRepository: "quay.io/giantswarm",
 							},
 						},
@@ -218,15 +241,15 @@ func transformKubeAdmControlPlane(gsCRs *giantswarm.GSClusterCrs, k8sVersion str
 						},
 					},
 				},
-				PreKubeadmCommands: []string{
+				PreKubeadmCommands: access.preKubeadmCommands([]string{
 					"hostnamectl set-hostname $(curl http://169.254.169.254/latest/meta-data/local-hostname) # set proper hostname - necessary for kubeProxy to detect node name",
 					"iptables -A PREROUTING -t nat  -p tcp --dport 6443 -j REDIRECT --to-port 443 # route traffic from 6443 to 443",
 					"/bin/sh /migration/join-existing-cluster.sh",
-				},
+				}),
 			},
 			Replicas: &replicas,
-			Version:  k8sVersion,
+			Version:  versions.Kubernetes,
 		},
 	}
-	return cp
+	return cp, nil
 }
@@ -0,0 +1,187 @@
+package capi
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	giantswarmawsalpha3 "github.com/giantswarm/apiextensions/pkg/apis/infrastructure/v1alpha2"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capiawsv1alpha3 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	apiv1alpha3 "sigs.k8s.io/cluster-api/api/v1alpha3"
+	kubeadmapiv1alpha3 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1alpha3"
+
+	"github.com/giantswarm/aws-gs-to-capi/giantswarm"
+)
+
+const (
+	// NodePoolModeMachinePool creates a node pool as experimental
+	// MachinePool, which requires the MachinePool feature gate of CAPI.
+	NodePoolModeMachinePool = "machinepool"
+	// NodePoolModeMachineDeployment creates a node pool as one
+	// MachineDeployment per subnet of the node pool.
+	NodePoolModeMachineDeployment = "machinedeployment"
+
+	// Annotations of the cluster-autoscaler for CAPI node groups.
+	autoscalerMinSizeAnnotation = "cluster.x-k8s.io/cluster-api-autoscaler-node-group-min-size"
+	autoscalerMaxSizeAnnotation = "cluster.x-k8s.io/cluster-api-autoscaler-node-group-max-size"
+)
+
+// NodePoolModes are the ways a GS node pool can be created in CAPI.
+var NodePoolModes = []string{
+	NodePoolModeMachinePool,
+	NodePoolModeMachineDeployment,
+}
+
+func isNodePoolMode(mode string) bool {
+	for _, m := range NodePoolModes {
+		if m == mode {
+			return true
+		}
+	}
+
+	return false
+}
+
+// machineDeployments returns one MachineDeployment and AWSMachineTemplate per
+// subnet of the node pool, using the availability zone of the subnet as
+// failure domain. The scaling limits of the node pool are spread evenly across
+// the subnets.
+func machineDeployments(d *giantswarmawsalpha3.AWSMachineDeployment, network giantswarm.NodePoolNetwork, access access, instanceProfile string, clusterID string, k8sVersion string, namespace string) ([]*apiv1alpha3.MachineDeployment, []*capiawsv1alpha3.AWSMachineTemplate) {
+	var mds []*apiv1alpha3.MachineDeployment
+	var templates []*capiawsv1alpha3.AWSMachineTemplate
+	for i, subnet := range network.Subnets {
+		name := machineDeploymentName(clusterID, d.Name, subnet.AvailabilityZone)
+		min := spread(d.Spec.NodePool.Scaling.Min, len(network.Subnets), i)
+		max := spread(d.Spec.NodePool.Scaling.Max, len(network.Subnets), i)
+
+		templates = append(templates, machineDeploymentAWSMachineTemplate(d, network.SecurityGroupID, subnet, access, instanceProfile, name, namespace))
+		mds = append(mds, machineDeployment(d, subnet, name, min, max, clusterID, k8sVersion, namespace))
+	}
+
+	return mds, templates
+}
+
+func machineDeployment(d *giantswarmawsalpha3.AWSMachineDeployment, subnet giantswarm.Subnet, name string, min int, max int, clusterID string, k8sVersion string, namespace string) *apiv1alpha3.MachineDeployment {
+	replicas := int32(min)
+	labels := map[string]string{
+		apiv1alpha3.ClusterLabelName:           clusterID,
+		apiv1alpha3.MachineDeploymentLabelName: name,
+	}
+
+	md := &apiv1alpha3.MachineDeployment{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "MachineDeployment",
+			APIVersion: apiv1alpha3.GroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Annotations: map[string]string{
+				autoscalerMinSizeAnnotation: strconv.Itoa(min),
+				autoscalerMaxSizeAnnotation: strconv.Itoa(max),
+			},
+		},
+		Spec: apiv1alpha3.MachineDeploymentSpec{
+			ClusterName: clusterID,
+			Replicas:    &replicas,
+			Selector: metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Template: apiv1alpha3.MachineTemplateSpec{
+				ObjectMeta: apiv1alpha3.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: apiv1alpha3.MachineSpec{
+					ClusterName:   clusterID,
+					Version:       &k8sVersion,
+					FailureDomain: aws.String(subnet.AvailabilityZone),
+					InfrastructureRef: v1.ObjectReference{
+						Name:       name,
+						Namespace:  namespace,
+						Kind:       "AWSMachineTemplate",
+						APIVersion: capiawsv1alpha3.GroupVersion.String(),
+					},
+					Bootstrap: apiv1alpha3.Bootstrap{
+						ConfigRef: &v1.ObjectReference{
+							Name:       machinePoolName(clusterID, d.Name),
+							Namespace:  namespace,
+							Kind:       "KubeadmConfigTemplate",
+							APIVersion: kubeadmapiv1alpha3.GroupVersion.String(),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	return md
+}
+
+func machineDeploymentAWSMachineTemplate(d *giantswarmawsalpha3.AWSMachineDeployment, securityGroupID string, subnet giantswarm.Subnet, access access, instanceProfile string, name string, namespace string) *capiawsv1alpha3.AWSMachineTemplate {
+	t := &capiawsv1alpha3.AWSMachineTemplate{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "AWSMachineTemplate",
+			APIVersion: capiawsv1alpha3.GroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: capiawsv1alpha3.AWSMachineTemplateSpec{
+			Template: capiawsv1alpha3.AWSMachineTemplateResource{
+				Spec: capiawsv1alpha3.AWSMachineSpec{
+					InstanceType:       d.Spec.Provider.Worker.InstanceType,
+					SSHKeyName:         access.sshKeyName,
+					IAMInstanceProfile: instanceProfile,
+					AdditionalSecurityGroups: []capiawsv1alpha3.AWSResourceReference{
+						{
+							ID: aws.String(securityGroupID),
+						},
+					},
+					Subnet: &capiawsv1alpha3.AWSResourceReference{
+						ID: aws.String(subnet.ID),
+					},
+				},
+			},
+		},
+	}
+
+	return t
+}
+
+func machineDeploymentKubeadmConfigTemplate(d *giantswarmawsalpha3.AWSMachineDeployment, access access, registration nodeRegistration, clusterID string, namespace string) *kubeadmapiv1alpha3.KubeadmConfigTemplate {
+	t := &kubeadmapiv1alpha3.KubeadmConfigTemplate{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "KubeadmConfigTemplate",
+			APIVersion: kubeadmapiv1alpha3.GroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      machinePoolName(clusterID, d.Name),
+			Namespace: namespace,
+		},
+		Spec: kubeadmapiv1alpha3.KubeadmConfigTemplateSpec{
+			Template: kubeadmapiv1alpha3.KubeadmConfigTemplateResource{
+				Spec: nodePoolKubeadmConfigSpec(access, registration, clusterID),
+			},
+		},
+	}
+
+	return t
+}
+
+// spread returns the share of total of the i-th of n parts. The remainder is
+// given to the first parts.
+func spread(total int, n int, i int) int {
+	share := total / n
+	if i < total%n {
+		share++
+	}
+
+	return share
+}
+
+func machineDeploymentName(clusterID string, nodePool string, availabilityZone string) string {
+	return fmt.Sprintf("%s-%s", machinePoolName(clusterID, nodePool), availabilityZone)
+}
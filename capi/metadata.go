@@ -0,0 +1,165 @@
+package capi
+
+import (
+	"strings"
+
+	giantswarmawsalpha3 "github.com/giantswarm/apiextensions/pkg/apis/infrastructure/v1alpha2"
+	"github.com/giantswarm/microerror"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/giantswarm/aws-gs-to-capi/giantswarm"
+)
+
+// Annotations with the descriptions of the GS cluster and node pools, as
+// used by the GS CAPI providers.
+const (
+	annotationClusterDescription  = "cluster.giantswarm.io/description"
+	annotationNodePoolDescription = "machine-pool.giantswarm.io/name"
+)
+
+// DefaultLabelPrefixes are the key prefixes of the labels of the GS CRs
+// which are copied to the CAPI objects, e.g. the organization, the cluster
+// and the release of the cluster.
+var DefaultLabelPrefixes = []string{
+	"giantswarm.io/",
+	"release.giantswarm.io/",
+}
+
+// reservedTagPrefixes are the prefixes of AWS tags which are not copied to
+// the CAPI objects. aws: tags cannot be set, the others are set by GS for its
+// own resources or by CAPA itself. The Name tag is set by CAPA as well.
+var reservedTagPrefixes = []string{
+	"aws:",
+	"kubernetes.io/cluster/",
+	"sigs.k8s.io/cluster-api-provider-aws/",
+	"giantswarm.io/stack",
+}
+
+// metadata is what is copied from the GS CRs to the CAPI objects.
+type metadata struct {
+	labels      map[string]string
+	annotations map[string]string
+	tags        map[string]string
+}
+
+// newClusterMetadata returns the metadata of the cluster objects, taken from
+// the GS Cluster and AWSCluster.
+func newClusterMetadata(gsCRs *giantswarm.GSClusterCrs, labelPrefixes []string, annotationPrefixes []string) metadata {
+	m := metadata{
+		labels:      map[string]string{},
+		annotations: map[string]string{},
+		tags:        map[string]string{},
+	}
+
+	if gsCRs.Cluster != nil {
+		copyMatching(m.labels, gsCRs.Cluster.Labels, labelPrefixes)
+		copyMatching(m.annotations, gsCRs.Cluster.Annotations, annotationPrefixes)
+	}
+	copyMatching(m.labels, gsCRs.AWSCluster.Labels, labelPrefixes)
+	copyMatching(m.annotations, gsCRs.AWSCluster.Annotations, annotationPrefixes)
+	if d := gsCRs.AWSCluster.Spec.Cluster.Description; d != "" {
+		m.annotations[annotationClusterDescription] = d
+	}
+
+	copyTags(m.tags, gsCRs.Network.Tags)
+
+	return m
+}
+
+// nodePool returns the metadata of the objects of a node pool, which extends
+// the cluster metadata with the one of the AWSMachineDeployment.
+func (m metadata) nodePool(md *giantswarmawsalpha3.AWSMachineDeployment, network giantswarm.NodePoolNetwork, labelPrefixes []string, annotationPrefixes []string) metadata {
+	np := metadata{
+		labels:      copyMap(m.labels),
+		annotations: copyMap(m.annotations),
+		tags:        copyMap(m.tags),
+	}
+	// The description of the cluster does not describe the node pool.
+	delete(np.annotations, annotationClusterDescription)
+
+	copyMatching(np.labels, md.Labels, labelPrefixes)
+	copyMatching(np.annotations, md.Annotations, annotationPrefixes)
+	if d := md.Spec.NodePool.Description; d != "" {
+		np.annotations[annotationNodePoolDescription] = d
+	}
+
+	copyTags(np.tags, network.Tags)
+
+	return np
+}
+
+// apply adds the labels and annotations to the objects. Labels and
+// annotations already set on the objects are kept.
+func (m metadata) apply(objs ...runtime.Object) error {
+	for _, o := range objs {
+		a, err := meta.Accessor(o)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		a.SetLabels(merge(m.labels, a.GetLabels()))
+		a.SetAnnotations(merge(m.annotations, a.GetAnnotations()))
+	}
+
+	return nil
+}
+
+// copyMatching copies the entries of from whose keys match one of the
+// prefixes.
+func copyMatching(to map[string]string, from map[string]string, prefixes []string) {
+	for k, v := range from {
+		for _, p := range prefixes {
+			if strings.HasPrefix(k, p) {
+				to[k] = v
+				break
+			}
+		}
+	}
+}
+
+func copyTags(to map[string]string, from map[string]string) {
+	for k, v := range from {
+		if !isReservedTag(k) {
+			to[k] = v
+		}
+	}
+}
+
+func isReservedTag(key string) bool {
+	if key == "Name" {
+		return true
+	}
+	for _, p := range reservedTagPrefixes {
+		if strings.HasPrefix(key, p) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func copyMap(m map[string]string) map[string]string {
+	c := map[string]string{}
+	for k, v := range m {
+		c[k] = v
+	}
+
+	return c
+}
+
+// merge returns the entries of all maps, later maps win. It returns nil when
+// there are no entries, so that empty maps are left out of the manifests.
+func merge(maps ...map[string]string) map[string]string {
+	var merged map[string]string
+	for _, m := range maps {
+		for k, v := range m {
+			if merged == nil {
+				merged = map[string]string{}
+			}
+			merged[k] = v
+		}
+	}
+
+	return merged
+}
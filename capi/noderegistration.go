@@ -0,0 +1,187 @@
+package capi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	giantswarmawsalpha3 "github.com/giantswarm/apiextensions/pkg/apis/infrastructure/v1alpha2"
+	"github.com/giantswarm/microerror"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/aws-gs-to-capi/giantswarm"
+)
+
+const (
+	labelMachineDeployment = "giantswarm.io/machine-deployment"
+)
+
+// defaultNodeLabels are the labels of all GS workers.
+var defaultNodeLabels = map[string]string{
+	"node.kubernetes.io/worker": "",
+	"role":                      "worker",
+}
+
+// gsNodeLabels are set by GS on every node, they are not copied from the
+// nodes of the GS cluster.
+var gsNodeLabels = []string{
+	"ip",
+	"aws-operator.giantswarm.io/version",
+}
+
+// kubeletNodeLabels are set by the kubelet and the cloud provider on every
+// node, so the new nodes have them without copying them.
+var kubeletNodeLabels = []string{
+	v1.LabelHostname,
+	v1.LabelOSStable,
+	v1.LabelArchStable,
+	v1.LabelInstanceType,
+	v1.LabelInstanceTypeStable,
+	v1.LabelZoneFailureDomain,
+	v1.LabelZoneFailureDomainStable,
+	v1.LabelZoneRegion,
+	v1.LabelZoneRegionStable,
+	"beta.kubernetes.io/arch",
+	"beta.kubernetes.io/os",
+}
+
+// systemTaintPrefixes are the prefixes of the taints set by Kubernetes and
+// the cluster autoscaler, they are not copied from the nodes of the GS
+// cluster.
+var systemTaintPrefixes = []string{
+	"node.kubernetes.io/",
+	"node.cloudprovider.kubernetes.io/",
+	"ToBeDeletedByClusterAutoscaler",
+	"DeletionCandidateOfClusterAutoscaler",
+}
+
+// nodeRegistration is how the nodes of a node pool register with the
+// cluster.
+type nodeRegistration struct {
+	labels map[string]string
+	taints []v1.Taint
+}
+
+// newNodeRegistration returns the registration of the nodes of a node pool.
+// The labels and taints of the nodes of the GS node pool are kept, labels and
+// taints given explicitly are added. Labels the kubelet cannot set are
+// dropped from the nodes with a warning and an error when given explicitly.
+func newNodeRegistration(md *giantswarmawsalpha3.AWSMachineDeployment, nodes giantswarm.NodePoolNodes, labels map[string]string, taints []v1.Taint) (nodeRegistration, []string, error) {
+	r := nodeRegistration{
+		labels: copyMap(defaultNodeLabels),
+	}
+	r.labels[labelMachineDeployment] = md.Name
+
+	var warnings []string
+	for _, k := range sortedKeys(nodes.Labels) {
+		switch {
+		case contains(gsNodeLabels, k) || contains(kubeletNodeLabels, k):
+		case isNodeLabel(k):
+			r.labels[k] = nodes.Labels[k]
+		default:
+			warnings = append(warnings, fmt.Sprintf("node pool %s has the node label %s, which the kubelet cannot set, it is dropped", md.Name, k))
+		}
+	}
+	for k, v := range labels {
+		if !isNodeLabel(k) {
+			return nodeRegistration{}, nil, microerror.Maskf(invalidConfigError, "label %s of node pool %s cannot be set by the kubelet", k, md.Name)
+		}
+		r.labels[k] = v
+	}
+
+	for _, t := range nodes.Taints {
+		if !isSystemTaint(t.Key) {
+			r.addTaint(t)
+		}
+	}
+	for _, t := range taints {
+		r.addTaint(t)
+	}
+
+	return r, warnings, nil
+}
+
+// addTaint adds the taint, replacing a taint with the same key and effect.
+func (r *nodeRegistration) addTaint(taint v1.Taint) {
+	for i, t := range r.taints {
+		if t.Key == taint.Key && t.Effect == taint.Effect {
+			r.taints[i] = taint
+			return
+		}
+	}
+
+	r.taints = append(r.taints, taint)
+}
+
+// nodeLabels returns the labels as value of the node-labels flag of the
+// kubelet, sorted so that the output is stable.
+func (r nodeRegistration) nodeLabels() string {
+	var labels []string
+	for k, v := range r.labels {
+		if v == "" {
+			labels = append(labels, k)
+		} else {
+			labels = append(labels, fmt.Sprintf("%s=%s", k, v))
+		}
+	}
+	sort.Strings(labels)
+
+	return strings.Join(labels, ",")
+}
+
+// isNodeLabel returns whether the kubelet may set the label and it is not
+// one the kubelet or the cloud provider set on their own. Labels in the
+// kubernetes.io and k8s.io namespaces are restricted to node.kubernetes.io
+// and kubelet.kubernetes.io by the NodeRestriction admission plugin.
+func isNodeLabel(key string) bool {
+	i := strings.Index(key, "/")
+	if i < 0 {
+		return true
+	}
+	domain := key[:i]
+
+	if !isDomain(domain, "kubernetes.io") && !isDomain(domain, "k8s.io") {
+		return true
+	}
+	if key == v1.LabelInstanceTypeStable {
+		return false
+	}
+
+	return isDomain(domain, "node.kubernetes.io") || isDomain(domain, "kubelet.kubernetes.io")
+}
+
+// isDomain returns whether domain is d or a subdomain of it.
+func isDomain(domain string, d string) bool {
+	return domain == d || strings.HasSuffix(domain, "."+d)
+}
+
+func isSystemTaint(key string) bool {
+	for _, p := range systemTaintPrefixes {
+		if strings.HasPrefix(key, p) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sortedKeys returns the keys of m sorted, so that warnings are stable.
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func contains(l []string, s string) bool {
+	for _, e := range l {
+		if e == s {
+			return true
+		}
+	}
+
+	return false
+}
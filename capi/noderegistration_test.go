@@ -0,0 +1,189 @@
+package capi
+
+import (
+	"reflect"
+	"testing"
+
+	giantswarmawsalpha3 "github.com/giantswarm/apiextensions/pkg/apis/infrastructure/v1alpha2"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/aws-gs-to-capi/giantswarm"
+)
+
+func newMachineDeployment(id string) *giantswarmawsalpha3.AWSMachineDeployment {
+	return &giantswarmawsalpha3.AWSMachineDeployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: id,
+		},
+	}
+}
+
+// Test_nodePoolKubeadmConfigSpec checks the node-labels and taints the nodes
+// of a node pool join the cluster with.
+func Test_nodePoolKubeadmConfigSpec(t *testing.T) {
+	testCases := []struct {
+		name             string
+		nodes            giantswarm.NodePoolNodes
+		labels           map[string]string
+		taints           []v1.Taint
+		expectedLabels   string
+		expectedTaints   []v1.Taint
+		expectedWarnings []string
+	}{
+		{
+			name:           "case 0: default labels",
+			expectedLabels: "giantswarm.io/machine-deployment=np001,node.kubernetes.io/worker,role=worker",
+		},
+		{
+			name: "case 1: labels and taints of the nodes",
+			nodes: giantswarm.NodePoolNodes{
+				Labels: map[string]string{
+					"team":                               "data",
+					"ip":                                 "10.1.1.10",
+					"aws-operator.giantswarm.io/version": "10.7.0",
+					"kubernetes.io/os":                   "linux",
+					"node-role.kubernetes.io/worker":     "",
+					"node.kubernetes.io/instance-type":   "m5.large",
+					"node.kubernetes.io/exclude-from-lb": "true",
+					"kubelet.kubernetes.io/custom":       "a",
+					"giantswarm.io/machine-deployment":   "np001",
+					"topology.kubernetes.io/zone":        "eu-west-1a",
+					"example.com/empty":                  "",
+					"node-restriction.kubernetes.io/gpu": "true",
+				},
+				Taints: []v1.Taint{
+					{Key: "dedicated", Value: "data", Effect: v1.TaintEffectNoSchedule},
+					{Key: "node.kubernetes.io/unschedulable", Effect: v1.TaintEffectNoSchedule},
+					{Key: "ToBeDeletedByClusterAutoscaler", Value: "1620000000", Effect: v1.TaintEffectNoSchedule},
+				},
+			},
+			expectedLabels: "example.com/empty,giantswarm.io/machine-deployment=np001,kubelet.kubernetes.io/custom=a,node.kubernetes.io/exclude-from-lb=true,node.kubernetes.io/worker,role=worker,team=data",
+			expectedTaints: []v1.Taint{
+				{Key: "dedicated", Value: "data", Effect: v1.TaintEffectNoSchedule},
+			},
+			expectedWarnings: []string{
+				"node-restriction.kubernetes.io/gpu",
+				"node-role.kubernetes.io/worker",
+			},
+		},
+		{
+			name: "case 2: given labels and taints win",
+			nodes: giantswarm.NodePoolNodes{
+				Labels: map[string]string{"team": "data"},
+				Taints: []v1.Taint{
+					{Key: "dedicated", Value: "data", Effect: v1.TaintEffectNoSchedule},
+					{Key: "dedicated", Value: "data", Effect: v1.TaintEffectNoExecute},
+				},
+			},
+			labels: map[string]string{"team": "web", "tier": ""},
+			taints: []v1.Taint{
+				{Key: "dedicated", Value: "web", Effect: v1.TaintEffectNoSchedule},
+				{Key: "gpu", Effect: v1.TaintEffectPreferNoSchedule},
+			},
+			expectedLabels: "giantswarm.io/machine-deployment=np001,node.kubernetes.io/worker,role=worker,team=web,tier",
+			expectedTaints: []v1.Taint{
+				{Key: "dedicated", Value: "web", Effect: v1.TaintEffectNoSchedule},
+				{Key: "dedicated", Value: "data", Effect: v1.TaintEffectNoExecute},
+				{Key: "gpu", Effect: v1.TaintEffectPreferNoSchedule},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			registration, warnings, err := newNodeRegistration(newMachineDeployment("np001"), tc.nodes, tc.labels, tc.taints)
+			if err != nil {
+				t.Fatalf("newNodeRegistration() error = %v", err)
+			}
+			checkWarnings(t, warnings, tc.expectedWarnings)
+
+			spec := nodePoolKubeadmConfigSpec(access{}, registration, "abc12")
+
+			args := spec.JoinConfiguration.NodeRegistration.KubeletExtraArgs
+			expectedArgs := map[string]string{
+				"cloud-provider": "aws",
+				"node-labels":    tc.expectedLabels,
+			}
+			if !reflect.DeepEqual(args, expectedArgs) {
+				t.Errorf("kubeletExtraArgs = %v, want %v", args, expectedArgs)
+			}
+			taints := spec.JoinConfiguration.NodeRegistration.Taints
+			if !reflect.DeepEqual(taints, tc.expectedTaints) {
+				t.Errorf("taints = %v, want %v", taints, tc.expectedTaints)
+			}
+		})
+	}
+}
+
+func Test_newNodeRegistration_RestrictedLabel(t *testing.T) {
+	for _, key := range []string{"node-role.kubernetes.io/worker", "kubernetes.io/os", "node.kubernetes.io/instance-type", "k8s.io/team"} {
+		t.Run(key, func(t *testing.T) {
+			_, _, err := newNodeRegistration(newMachineDeployment("np001"), giantswarm.NodePoolNodes{}, map[string]string{key: "a"}, nil)
+			if !IsInvalidConfig(err) {
+				t.Fatalf("newNodeRegistration() error = %v, want invalid config error", err)
+			}
+		})
+	}
+}
+
+func Test_checkNodePoolIDs(t *testing.T) {
+	gsCRs := &giantswarm.GSClusterCrs{
+		AWSCluster: &giantswarmawsalpha3.AWSCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "abc12"},
+		},
+		AWSMachineDeployments: []*giantswarmawsalpha3.AWSMachineDeployment{
+			newMachineDeployment("np001"),
+			newMachineDeployment("np002"),
+		},
+	}
+
+	testCases := []struct {
+		name   string
+		config Config
+		valid  bool
+	}{
+		{
+			name:  "case 0: no node pool settings",
+			valid: true,
+		},
+		{
+			name: "case 1: known node pools",
+			config: Config{
+				NodePoolModes:            map[string]string{"np001": NodePoolModeMachineDeployment},
+				NodePoolInstanceProfiles: map[string]string{"np002": "gpu"},
+				NodePoolLabels:           map[string]map[string]string{"np001": {"team": "data"}},
+				NodePoolTaints:           map[string][]v1.Taint{"np002": {{Key: "gpu", Effect: v1.TaintEffectNoSchedule}}},
+			},
+			valid: true,
+		},
+		{
+			name:   "case 2: labels of an unknown node pool",
+			config: Config{NodePoolLabels: map[string]map[string]string{"np003": {"team": "data"}}},
+		},
+		{
+			name:   "case 3: taints of an unknown node pool",
+			config: Config{NodePoolTaints: map[string][]v1.Taint{"np01": {{Key: "gpu", Effect: v1.TaintEffectNoSchedule}}}},
+		},
+		{
+			name:   "case 4: mode of an unknown node pool",
+			config: Config{NodePoolModes: map[string]string{"abc12": NodePoolModeMachinePool}},
+		},
+		{
+			name:   "case 5: instance profile of an unknown node pool",
+			config: Config{NodePoolInstanceProfiles: map[string]string{"NP001": "gpu"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkNodePoolIDs(gsCRs, tc.config)
+			if tc.valid && err != nil {
+				t.Fatalf("checkNodePoolIDs() error = %v", err)
+			}
+			if !tc.valid && !IsInvalidConfig(err) {
+				t.Fatalf("checkNodePoolIDs() error = %v, want invalid config error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,329 @@
+package capi
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/aws-gs-to-capi/ctrlclient"
+)
+
+const (
+	controlPlanePollInterval = 15 * time.Second
+	// controlPlaneReadyTimeout is the time a control plane machine has to
+	// join the cluster.
+	controlPlaneReadyTimeout = 30 * time.Minute
+)
+
+// ControlPlaneReplicas returns the replicas of the GS control plane, which
+// the new control plane is scaled up to.
+func (crs *Crs) ControlPlaneReplicas() int32 {
+	return *crs.ControlPlane.Spec.Replicas
+}
+
+// ControlPlaneAZs returns the availability zones the control plane machines
+// are spread across, which are the ones with private subnets of the
+// AWSCluster.
+func (crs *Crs) ControlPlaneAZs() []string {
+	var azs []string
+	seen := map[string]bool{}
+	for _, s := range crs.AWSCluster.Spec.NetworkSpec.Subnets {
+		if s.IsPublic || seen[s.AvailabilityZone] {
+			continue
+		}
+		seen[s.AvailabilityZone] = true
+		azs = append(azs, s.AvailabilityZone)
+	}
+
+	return azs
+}
+
+// withControlPlaneReplicas returns a copy of crs whose control plane has the
+// given replicas.
+func (crs *Crs) withControlPlaneReplicas(replicas int32) (*Crs, error) {
+	c := *crs
+
+	c.ControlPlane = crs.ControlPlane.DeepCopy()
+	c.ControlPlane.Spec.Replicas = &replicas
+
+	if crs.Topology != nil {
+		c.Topology = crs.Topology.DeepCopy()
+		err := unstructured.SetNestedField(c.Topology.Object, int64(replicas), "spec", "topology", "controlPlane", "replicas")
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	return &c, nil
+}
+
+// Initial returns crs as it is created on the CAPI management cluster, with
+// a single control plane replica. The control plane is grown to
+// ControlPlaneReplicas by ScaleControlPlane only, since creating all members
+// at once would break the etcd cluster of the GS cluster.
+func (crs *Crs) Initial() (*Crs, error) {
+	initial, err := crs.withControlPlaneReplicas(1)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return initial, nil
+}
+
+// Applied returns crs as it is applied to the CAPI management cluster. The
+// control plane keeps the replicas it has there, since it is only grown by
+// ScaleControlPlane. A new control plane starts with a single replica.
+func (crs *Crs) Applied(k8sContext string) (*Crs, error) {
+	c, err := ctrlclient.GetCtrlClient(k8sContext)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	current, err := currentControlPlaneReplicas(context.Background(), c, crs)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if current < 1 {
+		return crs.Initial()
+	}
+
+	applied, err := crs.withControlPlaneReplicas(current)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return applied, nil
+}
+
+// KeepControlPlaneReplicas sets the control plane replicas of rendered objects
+// to the ones the control plane has on the CAPI management cluster. Rendered
+// objects have a single replica, so applying them again after
+// ScaleControlPlane grew the control plane must not shrink it.
+func KeepControlPlaneReplicas(objs []runtime.Object, k8sContext string) error {
+	ctx := context.Background()
+	c, err := ctrlclient.GetCtrlClient(k8sContext)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	for _, o := range objs {
+		u, ok := o.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		path := controlPlaneReplicasPath(u)
+		if path == nil {
+			continue
+		}
+
+		current, err := currentReplicas(ctx, c, u, path)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		if current < 1 {
+			continue
+		}
+
+		err = unstructured.SetNestedField(u.Object, int64(current), path...)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// ScaleControlPlane grows the control plane on the CAPI management cluster to
+// the replicas of the GS control plane. Every new machine joins the etcd
+// cluster of the GS cluster, so the replicas are increased one at a time and
+// only after all machines are ready, which keeps the etcd quorum while the
+// members are added.
+func ScaleControlPlane(crs *Crs, k8sContext string) error {
+	ctx := context.Background()
+	c, err := ctrlclient.GetCtrlClient(k8sContext)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	desired := crs.ControlPlaneReplicas()
+	for {
+		current, err := currentControlPlaneReplicas(ctx, c, crs)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		if current == 0 {
+			return microerror.Maskf(executionFailedError, "control plane of cluster %s/%s does not exist", crs.Cluster.Namespace, crs.Cluster.Name)
+		}
+
+		err = waitForControlPlane(ctx, c, crs, current)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		if current >= desired {
+			fmt.Printf("Control plane has %d of %d replicas\n", current, desired)
+			return nil
+		}
+
+		fmt.Printf("Scaling control plane from %d to %d replicas\n", current, current+1)
+		scaled, err := crs.withControlPlaneReplicas(current + 1)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		objs, err := crs.Write([]runtime.Object{scaled.controlPlaneReplicasObject()})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		err = ApplyResources(objs, k8sContext)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+}
+
+// controlPlaneReplicasObject returns the object the replicas of the control
+// plane are set in, the Cluster of a topology or the KubeadmControlPlane.
+func (crs *Crs) controlPlaneReplicasObject() runtime.Object {
+	if crs.Topology != nil {
+		return crs.Topology
+	}
+
+	return crs.ControlPlane
+}
+
+// currentControlPlaneReplicas returns the replicas of the control plane on
+// the CAPI management cluster, or 0 when it does not exist yet.
+func currentControlPlaneReplicas(ctx context.Context, c client.Client, crs *Crs) (int32, error) {
+	objs, err := crs.Write([]runtime.Object{crs.controlPlaneReplicasObject()})
+	if err != nil {
+		return 0, microerror.Mask(err)
+	}
+
+	path := []string{"spec", "replicas"}
+	if crs.Topology != nil {
+		path = topologyReplicasPath
+	}
+
+	replicas, err := currentReplicas(ctx, c, objs[0], path)
+	if err != nil {
+		return 0, microerror.Mask(err)
+	}
+
+	return replicas, nil
+}
+
+var topologyReplicasPath = []string{"spec", "topology", "controlPlane", "replicas"}
+
+// controlPlaneReplicasPath returns the path of the control plane replicas in
+// a KubeadmControlPlane or the Cluster of a topology, nil for other objects.
+func controlPlaneReplicasPath(u *unstructured.Unstructured) []string {
+	gvk := u.GroupVersionKind()
+	switch {
+	case gvk.Kind == "KubeadmControlPlane" && isCAPIGroup(gvk.Group):
+		return []string{"spec", "replicas"}
+	case gvk.Kind == "Cluster" && gvk.Group == coreGroup:
+		_, found, _ := unstructured.NestedFieldNoCopy(u.Object, "spec", "topology")
+		if found {
+			return topologyReplicasPath
+		}
+	}
+
+	return nil
+}
+
+// currentReplicas returns the replicas at path of the object on the CAPI
+// management cluster, or 0 when it does not exist yet.
+func currentReplicas(ctx context.Context, c client.Client, obj runtime.Object, path []string) (int32, error) {
+	m, err := meta.Accessor(obj)
+	if err != nil {
+		return 0, microerror.Mask(err)
+	}
+
+	o := &unstructured.Unstructured{}
+	o.SetGroupVersionKind(obj.GetObjectKind().GroupVersionKind())
+	err = c.Get(ctx, client.ObjectKey{Namespace: m.GetNamespace(), Name: m.GetName()}, o)
+	if apierrors.IsNotFound(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, microerror.Mask(err)
+	}
+
+	replicas, _, err := unstructured.NestedInt64(o.Object, path...)
+	if err != nil {
+		return 0, microerror.Mask(err)
+	}
+
+	return int32(replicas), nil
+}
+
+// waitForControlPlane waits until the control plane of the Cluster has the
+// given number of machines and all of them are ready.
+func waitForControlPlane(ctx context.Context, c client.Client, crs *Crs, replicas int32) error {
+	objs, err := crs.Write([]runtime.Object{crs.Cluster})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	cluster := &unstructured.Unstructured{}
+	cluster.SetGroupVersionKind(objs[0].GetObjectKind().GroupVersionKind())
+
+	deadline := time.Now().Add(controlPlaneReadyTimeout)
+	for {
+		err = c.Get(ctx, client.ObjectKey{Namespace: crs.Cluster.Namespace, Name: crs.Cluster.Name}, cluster)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		ready, err := controlPlaneReady(ctx, c, cluster, replicas)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		if ready {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return microerror.Maskf(executionFailedError, "control plane of cluster %s/%s has not %d ready replicas after %s", crs.Cluster.Namespace, crs.Cluster.Name, replicas, controlPlaneReadyTimeout)
+		}
+		fmt.Printf("Waiting for %d ready control plane replicas, sleeping for %s ...\n", replicas, controlPlanePollInterval)
+		time.Sleep(controlPlanePollInterval)
+	}
+}
+
+// controlPlaneReady checks the status of the control plane referenced by the
+// Cluster. It is not ready as long as the reference is not set.
+func controlPlaneReady(ctx context.Context, c client.Client, cluster *unstructured.Unstructured, replicas int32) (bool, error) {
+	ref, found, err := unstructured.NestedStringMap(cluster.Object, "spec", "controlPlaneRef")
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+	if !found || ref["name"] == "" {
+		return false, nil
+	}
+
+	cp := &unstructured.Unstructured{}
+	cp.SetGroupVersionKind(schema.FromAPIVersionAndKind(ref["apiVersion"], ref["kind"]))
+	err = c.Get(ctx, client.ObjectKey{Namespace: cluster.GetNamespace(), Name: ref["name"]}, cp)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	status := map[string]int64{}
+	for _, field := range []string{"replicas", "readyReplicas", "unavailableReplicas"} {
+		status[field], _, err = unstructured.NestedInt64(cp.Object, "status", field)
+		if err != nil {
+			return false, microerror.Mask(err)
+		}
+	}
+
+	ready := status["replicas"] == int64(replicas) && status["readyReplicas"] == int64(replicas) && status["unavailableReplicas"] == 0
+	return ready, nil
+}
@@ -0,0 +1,94 @@
+package capi
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	kubeadmv1alpha3 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1alpha3"
+)
+
+func newTopology(replicas int64) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "cluster.x-k8s.io/v1beta1",
+		"kind":       "Cluster",
+		"spec": map[string]interface{}{
+			"topology": map[string]interface{}{
+				"controlPlane": map[string]interface{}{
+					"replicas": replicas,
+				},
+			},
+		},
+	}}
+
+	return u
+}
+
+func Test_Crs_Initial(t *testing.T) {
+	replicas := int32(3)
+	crs := &Crs{
+		ControlPlane: &kubeadmv1alpha3.KubeadmControlPlane{
+			Spec: kubeadmv1alpha3.KubeadmControlPlaneSpec{Replicas: &replicas},
+		},
+		Topology: newTopology(3),
+	}
+
+	initial, err := crs.Initial()
+	if err != nil {
+		t.Fatalf("Initial() error = %v", err)
+	}
+
+	if got := initial.ControlPlaneReplicas(); got != 1 {
+		t.Errorf("ControlPlaneReplicas() = %d, want 1", got)
+	}
+	got, _, err := unstructured.NestedInt64(initial.Topology.Object, topologyReplicasPath...)
+	if err != nil {
+		t.Fatalf("NestedInt64() error = %v", err)
+	}
+	if got != 1 {
+		t.Errorf("topology replicas = %d, want 1", got)
+	}
+
+	// The final replicas are kept, ScaleControlPlane grows the control plane
+	// to them.
+	if got := crs.ControlPlaneReplicas(); got != 3 {
+		t.Errorf("ControlPlaneReplicas() of the original = %d, want 3", got)
+	}
+	got, _, _ = unstructured.NestedInt64(crs.Topology.Object, topologyReplicasPath...)
+	if got != 3 {
+		t.Errorf("topology replicas of the original = %d, want 3", got)
+	}
+}
+
+func Test_controlPlaneReplicasPath(t *testing.T) {
+	kcp := &unstructured.Unstructured{}
+	kcp.SetAPIVersion("controlplane.cluster.x-k8s.io/v1beta1")
+	kcp.SetKind("KubeadmControlPlane")
+
+	cluster := &unstructured.Unstructured{}
+	cluster.SetAPIVersion("cluster.x-k8s.io/v1beta1")
+	cluster.SetKind("Cluster")
+
+	otherCluster := newTopology(3)
+	otherCluster.SetAPIVersion("example.com/v1")
+
+	testCases := []struct {
+		name     string
+		obj      *unstructured.Unstructured
+		expected []string
+	}{
+		{name: "case 0: KubeadmControlPlane", obj: kcp, expected: []string{"spec", "replicas"}},
+		{name: "case 1: Cluster with topology", obj: newTopology(3), expected: topologyReplicasPath},
+		{name: "case 2: Cluster without topology", obj: cluster},
+		{name: "case 3: Cluster of another group", obj: otherCluster},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := controlPlaneReplicasPath(tc.obj)
+			if !reflect.DeepEqual(path, tc.expected) {
+				t.Fatalf("controlPlaneReplicasPath() = %v, want %v", path, tc.expected)
+			}
+		})
+	}
+}
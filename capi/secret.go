@@ -26,6 +26,9 @@ type CustomFilesParams struct {
 	KubeProxyCA   string
 	KubeProxyKey  string
 	KubeProxyCrt  string
+	// EtcdctlVersion is the etcdctl release downloaded by the migration
+	// script, e.g. v3.4.14.
+	EtcdctlVersion string
 }
 
 func customFilesSecret(params CustomFilesParams) (v1.Secret, error) {
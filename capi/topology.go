@@ -0,0 +1,269 @@
+package capi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// defaultWorkerClass is the MachineDeployment class of the ClusterClass
+	// the node pools are created with.
+	defaultWorkerClass = "default-worker"
+)
+
+// Names of the variables of the ClusterClass the migration specific values
+// are passed in. The ClusterClass has to define all of them.
+const (
+	variableVPCID                       = "vpcID"
+	variableInternetGatewayID           = "internetGatewayID"
+	variableSubnets                     = "subnets"
+	variableControlPlaneSecurityGroupID = "controlPlaneSecurityGroupID"
+	variableControlPlaneInstanceType    = "controlPlaneInstanceType"
+	variableAPIServerCertSANs           = "apiServerCertSANs"
+	variableEtcdImageTag                = "etcdImageTag"
+	variableCustomFilesSecretName       = "customFilesSecretName"
+	variableSSHKeyName                  = "sshKeyName"
+	variableSSM                         = "ssm"
+	variableControlPlaneInstanceProfile = "controlPlaneInstanceProfile"
+	variableAdditionalTags              = "additionalTags"
+
+	variableNodePoolID      = "nodePoolID"
+	variableInstanceType    = "instanceType"
+	variableSecurityGroupID = "securityGroupID"
+	variableSubnetID        = "subnetID"
+	variableInstanceProfile = "instanceProfile"
+	variableNodeLabels      = "nodeLabels"
+	variableNodeTaints      = "nodeTaints"
+	variableSpotInstances   = "spotInstances"
+)
+
+// topologyCluster returns the v1beta1 Cluster with spec.topology referencing
+// the given ClusterClass, built from the internal representation in crs. All
+// node pools have to be in the machinedeployment mode, every MachineDeployment
+// becomes a MachineDeployment topology of workerClass.
+func topologyCluster(crs *Crs, access access, clusterClass string, workerClass string) (*unstructured.Unstructured, error) {
+	w, err := NewWriter(APIVersionV1beta1)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	o, err := w.Write(crs.Cluster)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	cluster := o.(*unstructured.Unstructured)
+
+	// The references and the endpoint are set by the controllers.
+	unstructured.RemoveNestedField(cluster.Object, "spec", "controlPlaneRef")
+	unstructured.RemoveNestedField(cluster.Object, "spec", "infrastructureRef")
+	unstructured.RemoveNestedField(cluster.Object, "spec", "controlPlaneEndpoint")
+
+	replicas := int64(1)
+	if crs.ControlPlane.Spec.Replicas != nil {
+		replicas = int64(*crs.ControlPlane.Spec.Replicas)
+	}
+
+	var workers []interface{}
+	for _, mp := range crs.MachinePools {
+		if mp.Mode != NodePoolModeMachineDeployment {
+			return nil, microerror.Maskf(invalidConfigError, "node pool %s must be in the %s mode to be part of a topology", mp.NodePoolID, NodePoolModeMachineDeployment)
+		}
+
+		for i := range mp.MachineDeployments {
+			workers = append(workers, machineDeploymentTopology(mp, i, workerClass))
+		}
+	}
+
+	topology := map[string]interface{}{
+		"class":   clusterClass,
+		"version": crs.Versions.Kubernetes,
+		"controlPlane": map[string]interface{}{
+			"replicas": replicas,
+		},
+		"variables": clusterVariables(crs, access),
+	}
+	if len(workers) > 0 {
+		topology["workers"] = map[string]interface{}{
+			"machineDeployments": workers,
+		}
+	}
+
+	err = unstructured.SetNestedField(cluster.Object, topology, "spec", "topology")
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return cluster, nil
+}
+
+func machineDeploymentTopology(mp *MachinePoolSpec, i int, workerClass string) map[string]interface{} {
+	md := mp.MachineDeployments[i]
+	spec := mp.AWSMachineTemplates[i].Spec.Template.Spec
+
+	var securityGroupID string
+	if len(spec.AdditionalSecurityGroups) > 0 && spec.AdditionalSecurityGroups[0].ID != nil {
+		securityGroupID = *spec.AdditionalSecurityGroups[0].ID
+	}
+	var subnetID string
+	if spec.Subnet != nil && spec.Subnet.ID != nil {
+		subnetID = *spec.Subnet.ID
+	}
+
+	annotations := map[string]interface{}{}
+	for k, v := range md.Annotations {
+		annotations[k] = v
+	}
+	metadata := map[string]interface{}{
+		"annotations": annotations,
+	}
+	if len(md.Labels) > 0 {
+		labels := map[string]interface{}{}
+		for k, v := range md.Labels {
+			labels[k] = v
+		}
+		metadata["labels"] = labels
+	}
+
+	overrides := map[string]interface{}{
+		variableNodePoolID:      mp.NodePoolID,
+		variableInstanceType:    spec.InstanceType,
+		variableSecurityGroupID: securityGroupID,
+		variableSubnetID:        subnetID,
+		variableInstanceProfile: spec.IAMInstanceProfile,
+	}
+	if len(spec.AdditionalTags) > 0 {
+		overrides[variableAdditionalTags] = tagsVariable(spec.AdditionalTags)
+	}
+	// Without the variable the ClusterClass decides about spot instances.
+	if spec.SpotMarketOptions != nil {
+		overrides[variableSpotInstances] = true
+	}
+	registration := mp.KubeadmConfigTemplate.Spec.Template.Spec.JoinConfiguration.NodeRegistration
+	overrides[variableNodeLabels] = registration.KubeletExtraArgs["node-labels"]
+	if len(registration.Taints) > 0 {
+		var taints []interface{}
+		for _, t := range registration.Taints {
+			taints = append(taints, map[string]interface{}{
+				"key":    t.Key,
+				"value":  t.Value,
+				"effect": string(t.Effect),
+			})
+		}
+		overrides[variableNodeTaints] = taints
+	}
+
+	t := map[string]interface{}{
+		"class": workerClass,
+		// The name has to be unique within the topology only, the controller
+		// prefixes it with the cluster name.
+		"name":     topologyName(md.Name, md.Spec.ClusterName),
+		"replicas": int64(*md.Spec.Replicas),
+		"metadata": metadata,
+		"variables": map[string]interface{}{
+			"overrides": variables(overrides),
+		},
+	}
+	if md.Spec.Template.Spec.FailureDomain != nil {
+		t["failureDomain"] = *md.Spec.Template.Spec.FailureDomain
+	}
+
+	return t
+}
+
+func clusterVariables(crs *Crs, access access) []interface{} {
+	network := crs.AWSCluster.Spec.NetworkSpec
+
+	var subnets []interface{}
+	for _, s := range network.Subnets {
+		subnets = append(subnets, map[string]interface{}{
+			"id":               s.ID,
+			"cidrBlock":        s.CidrBlock,
+			"availabilityZone": s.AvailabilityZone,
+			"isPublic":         s.IsPublic,
+		})
+	}
+
+	cpSpec := crs.ControlPlaneMachineTemplate.Spec.Template.Spec
+	var cpSecurityGroupID string
+	if len(cpSpec.AdditionalSecurityGroups) > 0 && cpSpec.AdditionalSecurityGroups[0].ID != nil {
+		cpSecurityGroupID = *cpSpec.AdditionalSecurityGroups[0].ID
+	}
+
+	var certSANs []interface{}
+	for _, san := range crs.ControlPlane.Spec.KubeadmConfigSpec.ClusterConfiguration.APIServer.CertSANs {
+		certSANs = append(certSANs, san)
+	}
+
+	vars := map[string]interface{}{
+		variableVPCID:                       network.VPC.ID,
+		variableSubnets:                     subnets,
+		variableControlPlaneSecurityGroupID: cpSecurityGroupID,
+		variableControlPlaneInstanceType:    cpSpec.InstanceType,
+		variableControlPlaneInstanceProfile: cpSpec.IAMInstanceProfile,
+		variableAPIServerCertSANs:           certSANs,
+		variableEtcdImageTag:                crs.Versions.Etcd,
+		variableCustomFilesSecretName:       crs.CustomFiles.Name,
+		variableSSM:                         access.ssm,
+	}
+	if network.VPC.InternetGatewayID != nil {
+		vars[variableInternetGatewayID] = *network.VPC.InternetGatewayID
+	}
+	if len(crs.AWSCluster.Spec.AdditionalTags) > 0 {
+		vars[variableAdditionalTags] = tagsVariable(crs.AWSCluster.Spec.AdditionalTags)
+	}
+	// Without the variable the ClusterClass decides about the key pair.
+	if access.sshKeyName != nil {
+		vars[variableSSHKeyName] = *access.sshKeyName
+	}
+
+	return variables(vars)
+}
+
+// variables returns the topology variables of the given values, sorted by
+// name so that the output is stable.
+func variables(values map[string]interface{}) []interface{} {
+	var names []string
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var vars []interface{}
+	for _, name := range names {
+		vars = append(vars, map[string]interface{}{
+			"name":  name,
+			"value": values[name],
+		})
+	}
+
+	return vars
+}
+
+func tagsVariable(tags map[string]string) map[string]interface{} {
+	v := map[string]interface{}{}
+	for k, t := range tags {
+		v[k] = t
+	}
+
+	return v
+}
+
+// withoutWorkers returns a copy of the topology Cluster without the
+// MachineDeployment topologies, which are only added with the node pools.
+func withoutWorkers(cluster *unstructured.Unstructured) runtime.Object {
+	c := cluster.DeepCopy()
+	unstructured.RemoveNestedField(c.Object, "spec", "topology", "workers")
+
+	return c
+}
+
+// topologyName returns the name of a MachineDeployment topology, which is
+// the MachineDeployment name without the cluster prefix.
+func topologyName(mdName string, clusterID string) string {
+	return strings.TrimPrefix(mdName, fmt.Sprintf("%s-", clusterID))
+}
@@ -0,0 +1,97 @@
+package capi
+
+import (
+	"fmt"
+
+	releasev1alpha1 "github.com/giantswarm/apiextensions/pkg/apis/release/v1alpha1"
+	"github.com/giantswarm/microerror"
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+const (
+	releaseComponentKubernetes = "kubernetes"
+	releaseComponentEtcd       = "etcd"
+)
+
+// Versions are the versions of the components of the new CAPI cluster. All
+// versions are prefixed with "v".
+type Versions struct {
+	// Release is the name of the GS Release CR of the source cluster.
+	Release string
+	// SourceKubernetes is the Kubernetes version of the GS release.
+	SourceKubernetes string
+	// Kubernetes is the Kubernetes version of the new cluster.
+	Kubernetes string
+	// Etcd is the tag of the etcd image of the new control plane. It matches
+	// the etcd version of the GS release since the new members join the
+	// existing etcd cluster.
+	Etcd string
+	// Etcdctl is the version of etcdctl the migration script uses to add the
+	// new members to the existing etcd cluster.
+	Etcdctl string
+}
+
+// ResolveVersions picks the versions of the new cluster from the GS Release
+// CR. k8sVersion overrides the Kubernetes version, but it must neither be a
+// downgrade nor skip a minor version, since the new control plane nodes join
+// the running cluster.
+func ResolveVersions(release *releasev1alpha1.Release, k8sVersion string) (Versions, error) {
+	if release == nil {
+		return Versions{}, microerror.Maskf(invalidVersionError, "the GS Release CR of the cluster is missing")
+	}
+
+	source, err := releaseComponent(release, releaseComponentKubernetes)
+	if err != nil {
+		return Versions{}, microerror.Mask(err)
+	}
+	etcd, err := releaseComponent(release, releaseComponentEtcd)
+	if err != nil {
+		return Versions{}, microerror.Mask(err)
+	}
+
+	target := source
+	if k8sVersion != "" {
+		target, err = version.ParseSemantic(k8sVersion)
+		if err != nil {
+			return Versions{}, microerror.Maskf(invalidVersionError, "invalid Kubernetes version %q: %s", k8sVersion, err)
+		}
+	}
+
+	if target.LessThan(source) {
+		return Versions{}, microerror.Maskf(invalidVersionError, "Kubernetes %s would be a downgrade from %s of GS release %s", vString(target), vString(source), release.Name)
+	}
+	if target.Major() != source.Major() || target.Minor() > source.Minor()+1 {
+		return Versions{}, microerror.Maskf(invalidVersionError, "Kubernetes %s is more than one minor version ahead of %s of GS release %s", vString(target), vString(source), release.Name)
+	}
+
+	v := Versions{
+		Release:          release.Name,
+		SourceKubernetes: vString(source),
+		Kubernetes:       vString(target),
+		Etcd:             vString(etcd),
+		Etcdctl:          vString(etcd),
+	}
+
+	return v, nil
+}
+
+func releaseComponent(release *releasev1alpha1.Release, name string) (*version.Version, error) {
+	for _, c := range release.Spec.Components {
+		if c.Name != name {
+			continue
+		}
+
+		v, err := version.ParseSemantic(c.Version)
+		if err != nil {
+			return nil, microerror.Maskf(invalidVersionError, "invalid version %q of component %s in GS release %s: %s", c.Version, name, release.Name, err)
+		}
+
+		return v, nil
+	}
+
+	return nil, microerror.Maskf(invalidVersionError, "GS release %s has no component %s", release.Name, name)
+}
+
+func vString(v *version.Version) string {
+	return fmt.Sprintf("v%s", v)
+}
@@ -0,0 +1,140 @@
+package capi
+
+import (
+	"testing"
+
+	releasev1alpha1 "github.com/giantswarm/apiextensions/pkg/apis/release/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newRelease(components ...releasev1alpha1.ReleaseSpecComponent) *releasev1alpha1.Release {
+	return &releasev1alpha1.Release{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "v14.1.0",
+		},
+		Spec: releasev1alpha1.ReleaseSpec{
+			Components: components,
+		},
+	}
+}
+
+var (
+	kubernetes1199 = releasev1alpha1.ReleaseSpecComponent{Name: "kubernetes", Version: "1.19.9"}
+	etcd3414       = releasev1alpha1.ReleaseSpecComponent{Name: "etcd", Version: "3.4.14"}
+)
+
+func Test_ResolveVersions(t *testing.T) {
+	testCases := []struct {
+		name       string
+		release    *releasev1alpha1.Release
+		k8sVersion string
+		expected   Versions
+	}{
+		{
+			name:    "case 0: versions of the release",
+			release: newRelease(kubernetes1199, etcd3414),
+			expected: Versions{
+				Release:          "v14.1.0",
+				SourceKubernetes: "v1.19.9",
+				Kubernetes:       "v1.19.9",
+				Etcd:             "v3.4.14",
+				Etcdctl:          "v3.4.14",
+			},
+		},
+		{
+			name:       "case 1: newer patch version",
+			release:    newRelease(etcd3414, kubernetes1199),
+			k8sVersion: "v1.19.16",
+			expected: Versions{
+				Release:          "v14.1.0",
+				SourceKubernetes: "v1.19.9",
+				Kubernetes:       "v1.19.16",
+				Etcd:             "v3.4.14",
+				Etcdctl:          "v3.4.14",
+			},
+		},
+		{
+			name:       "case 2: next minor version without v prefix",
+			release:    newRelease(kubernetes1199, etcd3414),
+			k8sVersion: "1.20.4",
+			expected: Versions{
+				Release:          "v14.1.0",
+				SourceKubernetes: "v1.19.9",
+				Kubernetes:       "v1.20.4",
+				Etcd:             "v3.4.14",
+				Etcdctl:          "v3.4.14",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := ResolveVersions(tc.release, tc.k8sVersion)
+			if err != nil {
+				t.Fatalf("ResolveVersions() error = %v", err)
+			}
+			if v != tc.expected {
+				t.Fatalf("ResolveVersions() = %#v, want %#v", v, tc.expected)
+			}
+		})
+	}
+}
+
+func Test_ResolveVersions_Errors(t *testing.T) {
+	testCases := []struct {
+		name       string
+		release    *releasev1alpha1.Release
+		k8sVersion string
+	}{
+		{
+			name:    "case 0: no release",
+			release: nil,
+		},
+		{
+			name:    "case 1: no kubernetes component",
+			release: newRelease(etcd3414),
+		},
+		{
+			name:    "case 2: no etcd component",
+			release: newRelease(kubernetes1199),
+		},
+		{
+			name:    "case 3: invalid component version",
+			release: newRelease(releasev1alpha1.ReleaseSpecComponent{Name: "kubernetes", Version: "latest"}, etcd3414),
+		},
+		{
+			name:       "case 4: invalid version",
+			release:    newRelease(kubernetes1199, etcd3414),
+			k8sVersion: "1.20",
+		},
+		{
+			name:       "case 5: patch downgrade",
+			release:    newRelease(kubernetes1199, etcd3414),
+			k8sVersion: "v1.19.8",
+		},
+		{
+			name:       "case 6: minor downgrade",
+			release:    newRelease(kubernetes1199, etcd3414),
+			k8sVersion: "v1.18.20",
+		},
+		{
+			name:       "case 7: two minor versions ahead",
+			release:    newRelease(kubernetes1199, etcd3414),
+			k8sVersion: "v1.21.0",
+		},
+		{
+			name:       "case 8: next major version",
+			release:    newRelease(kubernetes1199, etcd3414),
+			k8sVersion: "v2.0.0",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ResolveVersions(tc.release, tc.k8sVersion)
+			if !IsInvalidVersion(err) {
+				t.Fatalf("ResolveVersions() error = %v, want invalid version error", err)
+			}
+		})
+	}
+}
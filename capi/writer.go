@@ -0,0 +1,225 @@
+package capi
+
+import (
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	APIVersionV1alpha3 = "v1alpha3"
+	APIVersionV1alpha4 = "v1alpha4"
+	APIVersionV1beta1  = "v1beta1"
+
+	// capiGroupSuffix is the suffix of the API groups of CAPI and its
+	// providers, e.g. controlplane.cluster.x-k8s.io.
+	capiGroupSuffix = "cluster.x-k8s.io"
+	// expGroup is the group of the experimental CAPI types in v1alpha3. They
+	// moved to the core group with v1alpha4.
+	expGroup  = "exp.cluster.x-k8s.io"
+	coreGroup = "cluster.x-k8s.io"
+)
+
+// APIVersions are the CAPI API versions objects can be written in.
+var APIVersions = []string{
+	APIVersionV1alpha3,
+	APIVersionV1alpha4,
+	APIVersionV1beta1,
+}
+
+// Writer converts the objects of the internal representation, i.e. the
+// v1alpha3 objects built by TransformGsToCAPICrs, into the objects of one
+// CAPI API version.
+type Writer interface {
+	// APIVersion returns the CAPI API version the objects are written in,
+	// e.g. "v1beta1".
+	APIVersion() string
+	// Write converts o. Objects which are not CAPI objects, like Secrets, or
+	// which are already in the API version are returned unchanged.
+	Write(o runtime.Object) (runtime.Object, error)
+}
+
+// NewWriter returns the writer of the given CAPI API version.
+func NewWriter(apiVersion string) (Writer, error) {
+	switch apiVersion {
+	case APIVersionV1alpha3:
+		return v1alpha3Writer{}, nil
+	case APIVersionV1alpha4:
+		w := &conversionWriter{
+			apiVersion:  APIVersionV1alpha4,
+			conversions: v1alpha4Conversions,
+		}
+		return w, nil
+	case APIVersionV1beta1:
+		w := &conversionWriter{
+			apiVersion:  APIVersionV1beta1,
+			conversions: mergeConversions(v1alpha4Conversions, v1beta1Conversions),
+		}
+		return w, nil
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "CAPI API version must be one of %v but got %q", APIVersions, apiVersion)
+	}
+}
+
+// v1alpha3Writer writes the internal representation as it is.
+type v1alpha3Writer struct{}
+
+func (v1alpha3Writer) APIVersion() string {
+	return APIVersionV1alpha3
+}
+
+func (v1alpha3Writer) Write(o runtime.Object) (runtime.Object, error) {
+	return o, nil
+}
+
+// conversion changes the fields of an unstructured object in place.
+type conversion func(obj map[string]interface{}) error
+
+// conversionWriter writes unstructured objects of a newer CAPI API version.
+// The versions of the object and of all object references are replaced and
+// the conversions of the kind of the object are applied.
+type conversionWriter struct {
+	apiVersion  string
+	conversions map[string][]conversion
+}
+
+func (w *conversionWriter) APIVersion() string {
+	return w.apiVersion
+}
+
+func (w *conversionWriter) Write(o runtime.Object) (runtime.Object, error) {
+	gvk := o.GetObjectKind().GroupVersionKind()
+	if !isCAPIGroup(gvk.Group) || gvk.Version == w.apiVersion {
+		return o, nil
+	}
+
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(o)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	// The status of the newer versions differs and is not part of the
+	// desired state anyway.
+	delete(obj, "status")
+	unstructured.RemoveNestedField(obj, "metadata", "creationTimestamp")
+
+	w.replaceVersions(obj)
+
+	for _, c := range w.conversions[gvk.Kind] {
+		err = c(obj)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	return &unstructured.Unstructured{Object: obj}, nil
+}
+
+// replaceVersions replaces the version of every CAPI apiVersion field in the
+// object, which covers the object itself and all object references.
+func (w *conversionWriter) replaceVersions(v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if s, ok := v["apiVersion"].(string); ok {
+			gv, err := schema.ParseGroupVersion(s)
+			if err == nil && isCAPIGroup(gv.Group) {
+				if gv.Group == expGroup {
+					gv.Group = coreGroup
+				}
+				v["apiVersion"] = schema.GroupVersion{Group: gv.Group, Version: w.apiVersion}.String()
+			}
+		}
+		for _, value := range v {
+			w.replaceVersions(value)
+		}
+	case []interface{}:
+		for _, value := range v {
+			w.replaceVersions(value)
+		}
+	}
+}
+
+func isCAPIGroup(group string) bool {
+	return group == coreGroup || strings.HasSuffix(group, "."+capiGroupSuffix)
+}
+
+// v1alpha4Conversions are the changes from v1alpha3 to v1alpha4 of the
+// fields set by the transformation.
+var v1alpha4Conversions = map[string][]conversion{
+	"AWSCluster": {
+		moveField([]string{"spec", "networkSpec"}, []string{"spec", "network"}),
+	},
+	"AWSMachinePool": {
+		// The AMI reference of the launch template only has an ID.
+		removeField("spec", "awsLaunchTemplate", "ami", "arn"),
+		removeField("spec", "awsLaunchTemplate", "ami", "filters"),
+	},
+	"AWSMachineTemplate": {
+		removeField("spec", "template", "spec", "ami", "arn"),
+		removeField("spec", "template", "spec", "ami", "filters"),
+	},
+	"KubeadmConfig": {
+		removeField("spec", "clusterConfiguration", "dns", "type"),
+		removeField("spec", "clusterConfiguration", "useHyperKubeImage"),
+	},
+	"KubeadmControlPlane": {
+		moveField([]string{"spec", "infrastructureTemplate"}, []string{"spec", "machineTemplate", "infrastructureRef"}),
+		moveField([]string{"spec", "nodeDrainTimeout"}, []string{"spec", "machineTemplate", "nodeDrainTimeout"}),
+		moveField([]string{"spec", "upgradeAfter"}, []string{"spec", "rolloutAfter"}),
+		removeField("spec", "kubeadmConfigSpec", "clusterConfiguration", "dns", "type"),
+		removeField("spec", "kubeadmConfigSpec", "clusterConfiguration", "useHyperKubeImage"),
+	},
+}
+
+// v1beta1Conversions are the changes from v1alpha4 to v1beta1 of the fields
+// set by the transformation.
+var v1beta1Conversions = map[string][]conversion{
+	"KubeadmConfig": {
+		removeField("spec", "useExperimentalRetryJoin"),
+	},
+	"KubeadmControlPlane": {
+		removeField("spec", "kubeadmConfigSpec", "useExperimentalRetryJoin"),
+	},
+}
+
+func mergeConversions(all ...map[string][]conversion) map[string][]conversion {
+	merged := map[string][]conversion{}
+	for _, m := range all {
+		for kind, cs := range m {
+			merged[kind] = append(merged[kind], cs...)
+		}
+	}
+
+	return merged
+}
+
+// moveField moves the field at from to to, if it is set.
+func moveField(from []string, to []string) conversion {
+	return func(obj map[string]interface{}) error {
+		v, ok, err := unstructured.NestedFieldNoCopy(obj, from...)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		if !ok {
+			return nil
+		}
+
+		unstructured.RemoveNestedField(obj, from...)
+		err = unstructured.SetNestedField(obj, v, to...)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		return nil
+	}
+}
+
+func removeField(fields ...string) conversion {
+	return func(obj map[string]interface{}) error {
+		unstructured.RemoveNestedField(obj, fields...)
+		return nil
+	}
+}
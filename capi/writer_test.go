@@ -0,0 +1,416 @@
+package capi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	capiawsv1alpha3 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	capiawsexpv1alpha3 "sigs.k8s.io/cluster-api-provider-aws/exp/api/v1alpha3"
+	apiv1alpha3 "sigs.k8s.io/cluster-api/api/v1alpha3"
+	kubeadmapiv1alpha3 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1alpha3"
+	kubeadmtypev1beta1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/types/v1beta1"
+	kubeadmv1alpha3 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1alpha3"
+	expapiv1alpha3 "sigs.k8s.io/cluster-api/exp/api/v1alpha3"
+)
+
+func testMeta(name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Name: name, Namespace: "org-acme"}
+}
+
+func testRef(kind string, apiVersion string) v1.ObjectReference {
+	return v1.ObjectReference{Kind: kind, APIVersion: apiVersion, Name: "abc12", Namespace: "org-acme"}
+}
+
+var (
+	testAMI = capiawsv1alpha3.AWSResourceReference{
+		ID:      strPtr("ami-1"),
+		ARN:     strPtr("arn:aws:ec2:eu-west-1::image/ami-1"),
+		Filters: []capiawsv1alpha3.Filter{{Name: "name", Values: []string{"flatcar"}}},
+	}
+	testClusterConfiguration = &kubeadmtypev1beta1.ClusterConfiguration{
+		DNS:               kubeadmtypev1beta1.DNS{Type: kubeadmtypev1beta1.CoreDNS},
+		UseHyperKubeImage: true,
+	}
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+// Test_Writer_Write writes the v1alpha3 objects of every kind built by the
+// transformation in the newer versions and checks the fields which were
+// moved, renamed or removed, and the versions of the object references.
+// fields maps the paths of fields to their expected values, nil means the
+// field must not be set.
+func Test_Writer_Write(t *testing.T) {
+	upgradeAfter := metav1.NewTime(time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC))
+
+	testCases := []struct {
+		name   string
+		obj    runtime.Object
+		fields map[string]map[string]interface{}
+	}{
+		{
+			name: "case 0: Cluster",
+			obj: &apiv1alpha3.Cluster{
+				TypeMeta:   metav1.TypeMeta{Kind: "Cluster", APIVersion: apiv1alpha3.GroupVersion.String()},
+				ObjectMeta: testMeta("abc12"),
+				Spec: apiv1alpha3.ClusterSpec{
+					ControlPlaneRef:   refPtr(testRef("KubeadmControlPlane", kubeadmv1alpha3.GroupVersion.String())),
+					InfrastructureRef: refPtr(testRef("AWSCluster", capiawsv1alpha3.GroupVersion.String())),
+				},
+			},
+			fields: map[string]map[string]interface{}{
+				APIVersionV1alpha4: {
+					"apiVersion":                        "cluster.x-k8s.io/v1alpha4",
+					"spec.controlPlaneRef.apiVersion":   "controlplane.cluster.x-k8s.io/v1alpha4",
+					"spec.infrastructureRef.apiVersion": "infrastructure.cluster.x-k8s.io/v1alpha4",
+				},
+				APIVersionV1beta1: {
+					"apiVersion":                        "cluster.x-k8s.io/v1beta1",
+					"spec.controlPlaneRef.apiVersion":   "controlplane.cluster.x-k8s.io/v1beta1",
+					"spec.infrastructureRef.apiVersion": "infrastructure.cluster.x-k8s.io/v1beta1",
+				},
+			},
+		},
+		{
+			name: "case 1: AWSCluster",
+			obj: &capiawsv1alpha3.AWSCluster{
+				TypeMeta:   metav1.TypeMeta{Kind: "AWSCluster", APIVersion: capiawsv1alpha3.GroupVersion.String()},
+				ObjectMeta: testMeta("abc12"),
+				Spec: capiawsv1alpha3.AWSClusterSpec{
+					Region: "eu-west-1",
+					NetworkSpec: capiawsv1alpha3.NetworkSpec{
+						VPC: capiawsv1alpha3.VPCSpec{ID: "vpc-1"},
+					},
+				},
+			},
+			fields: map[string]map[string]interface{}{
+				APIVersionV1alpha4: {
+					"apiVersion":          "infrastructure.cluster.x-k8s.io/v1alpha4",
+					"spec.network.vpc.id": "vpc-1",
+					"spec.networkSpec":    nil,
+					"spec.region":         "eu-west-1",
+				},
+				APIVersionV1beta1: {
+					"apiVersion":          "infrastructure.cluster.x-k8s.io/v1beta1",
+					"spec.network.vpc.id": "vpc-1",
+					"spec.networkSpec":    nil,
+				},
+			},
+		},
+		{
+			name: "case 2: KubeadmControlPlane",
+			obj: &kubeadmv1alpha3.KubeadmControlPlane{
+				TypeMeta:   metav1.TypeMeta{Kind: "KubeadmControlPlane", APIVersion: kubeadmv1alpha3.GroupVersion.String()},
+				ObjectMeta: testMeta("abc12"),
+				Spec: kubeadmv1alpha3.KubeadmControlPlaneSpec{
+					Version:                "v1.19.9",
+					InfrastructureTemplate: testRef("AWSMachineTemplate", capiawsv1alpha3.GroupVersion.String()),
+					NodeDrainTimeout:       &metav1.Duration{Duration: 5 * time.Minute},
+					UpgradeAfter:           &upgradeAfter,
+					KubeadmConfigSpec: kubeadmapiv1alpha3.KubeadmConfigSpec{
+						ClusterConfiguration:     testClusterConfiguration,
+						UseExperimentalRetryJoin: true,
+					},
+				},
+			},
+			fields: map[string]map[string]interface{}{
+				APIVersionV1alpha4: {
+					"apiVersion": "controlplane.cluster.x-k8s.io/v1alpha4",
+					"spec.machineTemplate.infrastructureRef.apiVersion":             "infrastructure.cluster.x-k8s.io/v1alpha4",
+					"spec.machineTemplate.infrastructureRef.kind":                   "AWSMachineTemplate",
+					"spec.machineTemplate.nodeDrainTimeout":                         "5m0s",
+					"spec.rolloutAfter":                                             "2021-06-01T10:00:00Z",
+					"spec.infrastructureTemplate":                                   nil,
+					"spec.nodeDrainTimeout":                                         nil,
+					"spec.upgradeAfter":                                             nil,
+					"spec.kubeadmConfigSpec.clusterConfiguration.dns.type":          nil,
+					"spec.kubeadmConfigSpec.clusterConfiguration.useHyperKubeImage": nil,
+					"spec.kubeadmConfigSpec.useExperimentalRetryJoin":               true,
+				},
+				APIVersionV1beta1: {
+					"apiVersion": "controlplane.cluster.x-k8s.io/v1beta1",
+					"spec.machineTemplate.infrastructureRef.apiVersion":             "infrastructure.cluster.x-k8s.io/v1beta1",
+					"spec.machineTemplate.nodeDrainTimeout":                         "5m0s",
+					"spec.rolloutAfter":                                             "2021-06-01T10:00:00Z",
+					"spec.infrastructureTemplate":                                   nil,
+					"spec.kubeadmConfigSpec.clusterConfiguration.dns.type":          nil,
+					"spec.kubeadmConfigSpec.clusterConfiguration.useHyperKubeImage": nil,
+					"spec.kubeadmConfigSpec.useExperimentalRetryJoin":               nil,
+				},
+			},
+		},
+		{
+			name: "case 3: AWSMachineTemplate",
+			obj: &capiawsv1alpha3.AWSMachineTemplate{
+				TypeMeta:   metav1.TypeMeta{Kind: "AWSMachineTemplate", APIVersion: capiawsv1alpha3.GroupVersion.String()},
+				ObjectMeta: testMeta("abc12-control-plane"),
+				Spec: capiawsv1alpha3.AWSMachineTemplateSpec{
+					Template: capiawsv1alpha3.AWSMachineTemplateResource{
+						Spec: capiawsv1alpha3.AWSMachineSpec{
+							InstanceType: "m5.xlarge",
+							AMI:          testAMI,
+						},
+					},
+				},
+			},
+			fields: map[string]map[string]interface{}{
+				APIVersionV1alpha4: {
+					"apiVersion":                      "infrastructure.cluster.x-k8s.io/v1alpha4",
+					"spec.template.spec.ami.id":       "ami-1",
+					"spec.template.spec.ami.arn":      nil,
+					"spec.template.spec.ami.filters":  nil,
+					"spec.template.spec.instanceType": "m5.xlarge",
+				},
+				APIVersionV1beta1: {
+					"apiVersion":                     "infrastructure.cluster.x-k8s.io/v1beta1",
+					"spec.template.spec.ami.id":      "ami-1",
+					"spec.template.spec.ami.arn":     nil,
+					"spec.template.spec.ami.filters": nil,
+				},
+			},
+		},
+		{
+			name: "case 4: MachineDeployment",
+			obj: &apiv1alpha3.MachineDeployment{
+				TypeMeta:   metav1.TypeMeta{Kind: "MachineDeployment", APIVersion: apiv1alpha3.GroupVersion.String()},
+				ObjectMeta: testMeta("abc12-np001-a"),
+				Spec: apiv1alpha3.MachineDeploymentSpec{
+					ClusterName: "abc12",
+					Template: apiv1alpha3.MachineTemplateSpec{
+						Spec: apiv1alpha3.MachineSpec{
+							ClusterName:       "abc12",
+							InfrastructureRef: testRef("AWSMachineTemplate", capiawsv1alpha3.GroupVersion.String()),
+							Bootstrap: apiv1alpha3.Bootstrap{
+								ConfigRef: refPtr(testRef("KubeadmConfigTemplate", kubeadmapiv1alpha3.GroupVersion.String())),
+							},
+						},
+					},
+				},
+			},
+			fields: map[string]map[string]interface{}{
+				APIVersionV1alpha4: {
+					"apiVersion": "cluster.x-k8s.io/v1alpha4",
+					"spec.template.spec.infrastructureRef.apiVersion":   "infrastructure.cluster.x-k8s.io/v1alpha4",
+					"spec.template.spec.bootstrap.configRef.apiVersion": "bootstrap.cluster.x-k8s.io/v1alpha4",
+				},
+				APIVersionV1beta1: {
+					"apiVersion": "cluster.x-k8s.io/v1beta1",
+					"spec.template.spec.infrastructureRef.apiVersion":   "infrastructure.cluster.x-k8s.io/v1beta1",
+					"spec.template.spec.bootstrap.configRef.apiVersion": "bootstrap.cluster.x-k8s.io/v1beta1",
+				},
+			},
+		},
+		{
+			name: "case 5: MachinePool moves from the exp group",
+			obj: &expapiv1alpha3.MachinePool{
+				TypeMeta:   metav1.TypeMeta{Kind: "MachinePool", APIVersion: expapiv1alpha3.GroupVersion.String()},
+				ObjectMeta: testMeta("abc12-np001"),
+				Spec: expapiv1alpha3.MachinePoolSpec{
+					ClusterName: "abc12",
+					Template: apiv1alpha3.MachineTemplateSpec{
+						Spec: apiv1alpha3.MachineSpec{
+							ClusterName:       "abc12",
+							InfrastructureRef: testRef("AWSMachinePool", capiawsexpv1alpha3.GroupVersion.String()),
+							Bootstrap: apiv1alpha3.Bootstrap{
+								ConfigRef: refPtr(testRef("KubeadmConfig", kubeadmapiv1alpha3.GroupVersion.String())),
+							},
+						},
+					},
+				},
+			},
+			fields: map[string]map[string]interface{}{
+				APIVersionV1alpha4: {
+					"apiVersion": "cluster.x-k8s.io/v1alpha4",
+					"spec.template.spec.infrastructureRef.apiVersion":   "infrastructure.cluster.x-k8s.io/v1alpha4",
+					"spec.template.spec.bootstrap.configRef.apiVersion": "bootstrap.cluster.x-k8s.io/v1alpha4",
+				},
+				APIVersionV1beta1: {
+					"apiVersion": "cluster.x-k8s.io/v1beta1",
+					"spec.template.spec.infrastructureRef.apiVersion":   "infrastructure.cluster.x-k8s.io/v1beta1",
+					"spec.template.spec.bootstrap.configRef.apiVersion": "bootstrap.cluster.x-k8s.io/v1beta1",
+				},
+			},
+		},
+		{
+			name: "case 6: AWSMachinePool",
+			obj: &capiawsexpv1alpha3.AWSMachinePool{
+				TypeMeta:   metav1.TypeMeta{Kind: "AWSMachinePool", APIVersion: capiawsexpv1alpha3.GroupVersion.String()},
+				ObjectMeta: testMeta("abc12-np001"),
+				Spec: capiawsexpv1alpha3.AWSMachinePoolSpec{
+					MinSize: 2,
+					MaxSize: 5,
+					AWSLaunchTemplate: capiawsexpv1alpha3.AWSLaunchTemplate{
+						InstanceType: "m5.large",
+						AMI:          testAMI,
+					},
+				},
+			},
+			fields: map[string]map[string]interface{}{
+				APIVersionV1alpha4: {
+					"apiVersion":                          "infrastructure.cluster.x-k8s.io/v1alpha4",
+					"spec.awsLaunchTemplate.ami.id":       "ami-1",
+					"spec.awsLaunchTemplate.ami.arn":      nil,
+					"spec.awsLaunchTemplate.ami.filters":  nil,
+					"spec.awsLaunchTemplate.instanceType": "m5.large",
+					"spec.minSize":                        int64(2),
+				},
+				APIVersionV1beta1: {
+					"apiVersion":                         "infrastructure.cluster.x-k8s.io/v1beta1",
+					"spec.awsLaunchTemplate.ami.id":      "ami-1",
+					"spec.awsLaunchTemplate.ami.arn":     nil,
+					"spec.awsLaunchTemplate.ami.filters": nil,
+				},
+			},
+		},
+		{
+			name: "case 7: KubeadmConfig",
+			obj: &kubeadmapiv1alpha3.KubeadmConfig{
+				TypeMeta:   metav1.TypeMeta{Kind: "KubeadmConfig", APIVersion: kubeadmapiv1alpha3.GroupVersion.String()},
+				ObjectMeta: testMeta("abc12-np001"),
+				Spec: kubeadmapiv1alpha3.KubeadmConfigSpec{
+					ClusterConfiguration:     testClusterConfiguration,
+					UseExperimentalRetryJoin: true,
+				},
+			},
+			fields: map[string]map[string]interface{}{
+				APIVersionV1alpha4: {
+					"apiVersion":                                  "bootstrap.cluster.x-k8s.io/v1alpha4",
+					"spec.clusterConfiguration.dns.type":          nil,
+					"spec.clusterConfiguration.useHyperKubeImage": nil,
+					"spec.useExperimentalRetryJoin":               true,
+				},
+				APIVersionV1beta1: {
+					"apiVersion":                                  "bootstrap.cluster.x-k8s.io/v1beta1",
+					"spec.clusterConfiguration.dns.type":          nil,
+					"spec.clusterConfiguration.useHyperKubeImage": nil,
+					"spec.useExperimentalRetryJoin":               nil,
+				},
+			},
+		},
+		{
+			name: "case 8: KubeadmConfigTemplate",
+			obj: &kubeadmapiv1alpha3.KubeadmConfigTemplate{
+				TypeMeta:   metav1.TypeMeta{Kind: "KubeadmConfigTemplate", APIVersion: kubeadmapiv1alpha3.GroupVersion.String()},
+				ObjectMeta: testMeta("abc12-np001"),
+				Spec: kubeadmapiv1alpha3.KubeadmConfigTemplateSpec{
+					Template: kubeadmapiv1alpha3.KubeadmConfigTemplateResource{
+						Spec: kubeadmapiv1alpha3.KubeadmConfigSpec{
+							JoinConfiguration: &kubeadmtypev1beta1.JoinConfiguration{
+								NodeRegistration: kubeadmtypev1beta1.NodeRegistrationOptions{
+									Name: "{{ ds.meta_data.local_hostname }}",
+								},
+							},
+						},
+					},
+				},
+			},
+			fields: map[string]map[string]interface{}{
+				APIVersionV1alpha4: {
+					"apiVersion": "bootstrap.cluster.x-k8s.io/v1alpha4",
+					"spec.template.spec.joinConfiguration.nodeRegistration.name": "{{ ds.meta_data.local_hostname }}",
+				},
+				APIVersionV1beta1: {
+					"apiVersion": "bootstrap.cluster.x-k8s.io/v1beta1",
+					"spec.template.spec.joinConfiguration.nodeRegistration.name": "{{ ds.meta_data.local_hostname }}",
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		for _, apiVersion := range []string{APIVersionV1alpha4, APIVersionV1beta1} {
+			t.Run(tc.name+" "+apiVersion, func(t *testing.T) {
+				w, err := NewWriter(apiVersion)
+				if err != nil {
+					t.Fatalf("NewWriter() error = %v", err)
+				}
+
+				o, err := w.Write(tc.obj.DeepCopyObject())
+				if err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+				u, ok := o.(*unstructured.Unstructured)
+				if !ok {
+					t.Fatalf("Write() = %T, want *unstructured.Unstructured", o)
+				}
+				if _, ok := u.Object["status"]; ok {
+					t.Errorf("status is set, want it removed")
+				}
+
+				for path, want := range tc.fields[apiVersion] {
+					got, found, err := unstructured.NestedFieldNoCopy(u.Object, strings.Split(path, ".")...)
+					if err != nil {
+						t.Fatalf("NestedFieldNoCopy(%s) error = %v", path, err)
+					}
+					if want == nil {
+						if found {
+							t.Errorf("%s = %#v, want it not set", path, got)
+						}
+						continue
+					}
+					if !reflect.DeepEqual(got, want) {
+						t.Errorf("%s = %#v, want %#v", path, got, want)
+					}
+				}
+			})
+		}
+	}
+}
+
+func Test_Writer_Write_Unchanged(t *testing.T) {
+	secret := &v1.Secret{
+		TypeMeta:   metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"},
+		ObjectMeta: testMeta("abc12-ca"),
+	}
+
+	cluster := &unstructured.Unstructured{}
+	cluster.SetAPIVersion("cluster.x-k8s.io/v1beta1")
+	cluster.SetKind("Cluster")
+	cluster.SetName("abc12")
+
+	testCases := []struct {
+		name       string
+		apiVersion string
+		obj        runtime.Object
+	}{
+		{name: "case 0: v1alpha3", apiVersion: APIVersionV1alpha3, obj: &apiv1alpha3.Cluster{TypeMeta: metav1.TypeMeta{Kind: "Cluster", APIVersion: apiv1alpha3.GroupVersion.String()}}},
+		{name: "case 1: Secret", apiVersion: APIVersionV1beta1, obj: secret},
+		{name: "case 2: already in the API version", apiVersion: APIVersionV1beta1, obj: cluster},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w, err := NewWriter(tc.apiVersion)
+			if err != nil {
+				t.Fatalf("NewWriter() error = %v", err)
+			}
+
+			o, err := w.Write(tc.obj)
+			if err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if o != tc.obj {
+				t.Fatalf("Write() = %#v, want the object unchanged", o)
+			}
+		})
+	}
+}
+
+func Test_NewWriter_InvalidAPIVersion(t *testing.T) {
+	_, err := NewWriter("v1alpha2")
+	if !IsInvalidConfig(err) {
+		t.Fatalf("NewWriter() error = %v, want invalid config error", err)
+	}
+}
+
+func refPtr(r v1.ObjectReference) *v1.ObjectReference {
+	return &r
+}
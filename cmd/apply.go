@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/giantswarm/aws-gs-to-capi/capi"
+	"github.com/giantswarm/aws-gs-to-capi/render"
+)
+
+type applyFlags struct {
+	AgeIdentityFile string
+	Context         string
+	FromDir         string
+}
+
+func (f *applyFlags) validate() error {
+	if f.FromDir == "" {
+		return microerror.Maskf(invalidFlagError, "--from-dir must not be empty")
+	}
+
+	return validateContext(f.Context)
+}
+
+func newApplyCommand(rf *rootFlags) *cobra.Command {
+	var f applyFlags
+
+	c := &cobra.Command{
+		Use:   "apply",
+		Short: "Apply the objects of a rendered bundle.",
+		Long: `Apply the objects of a bundle written by "render --output-dir".
+
+The objects are applied with server-side apply, so the command can be run
+again after fixing a problem. The control plane keeps the replicas it already
+has, a new one is created with the single replica of the rendered bundle and
+grown with "update cp".
+
+Secrets encrypted with --age-recipient are decrypted with the age identities
+in --age-identity-file. The bundle must belong to the cluster given with
+--cluster-id.`,
+		Args: cobra.NoArgs,
+		PreRunE: func(c *cobra.Command, args []string) error {
+			return f.validate()
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			// The identity file is optional as long as the bundle does not
+			// contain encrypted secrets.
+			identities, err := readIdentities(f.AgeIdentityFile)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			objs, err := render.ReadDir(f.FromDir, identities)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			err = validateBundle(objs, rf.ClusterID)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			err = capi.KeepControlPlaneReplicas(objs, f.Context)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			err = capi.ApplyResources(objs, f.Context)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+			fmt.Printf("Applied %d objects from %s\n", len(objs), f.FromDir)
+
+			return nil
+		},
+	}
+
+	addAgeIdentityFileFlag(c, &f.AgeIdentityFile)
+	addContextFlag(c, &f.Context)
+	c.Flags().StringVar(&f.FromDir, "from-dir", "", "Directory written by render --output-dir.")
+
+	return c
+}
+
+// validateBundle makes sure the bundle contains the Cluster object of the
+// given cluster, so that a bundle of another cluster is not applied by
+// accident.
+func validateBundle(objs []runtime.Object, clusterID string) error {
+	for _, o := range objs {
+		if o.GetObjectKind().GroupVersionKind().Kind != "Cluster" {
+			continue
+		}
+
+		m, err := meta.Accessor(o)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		if m.GetName() == clusterID {
+			return nil
+		}
+	}
+
+	return microerror.Maskf(invalidFlagError, "bundle does not contain the Cluster %q", clusterID)
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/aws-gs-to-capi/state"
+)
+
+type createFlags struct {
+	migrationFlags
+	preflightFlags
+
+	AWSRegion  string
+	K8sVersion string
+}
+
+func newCreateCommand(rf *rootFlags) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "create",
+		Short: "Create CAPI resources for the migrated cluster.",
+		Long: `Create CAPI resources for the migrated cluster.
+
+The resources are applied with server-side apply, so every command can be run
+again to converge to the desired resources, e.g. after fixing a problem.`,
+		Args: cobra.NoArgs,
+		RunE: usage,
+	}
+
+	c.AddCommand(newCreateCPCommand(rf))
+	c.AddCommand(newCreateNPCommand(rf))
+	c.AddCommand(newCreateAllCommand(rf))
+
+	return c
+}
+
+func newCreateCPCommand(rf *rootFlags) *cobra.Command {
+	var f createFlags
+
+	c := &cobra.Command{
+		Use:   "cp",
+		Short: "Create the control plane resources and secrets.",
+		Args:  cobra.NoArgs,
+		PreRunE: func(c *cobra.Command, args []string) error {
+			return f.validate()
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			m, err := newMigration(rf, &f.migrationFlags, "", f.K8sVersion)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			err = m.run(&f.preflightFlags, state.PhaseControlPlane)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			return nil
+		},
+	}
+
+	f.migrationFlags.add(c)
+	f.preflightFlags.add(c)
+	c.Flags().StringVar(&f.K8sVersion, "k8s-version", "", "Kubernetes version of the new CAPI cluster. Defaults to the version of the GS release of the cluster.")
+
+	return c
+}
+
+func newCreateNPCommand(rf *rootFlags) *cobra.Command {
+	var f createFlags
+
+	c := &cobra.Command{
+		Use:   "np",
+		Short: "Create the node pool resources.",
+		Args:  cobra.NoArgs,
+		PreRunE: func(c *cobra.Command, args []string) error {
+			return f.validate()
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			m, err := newMigration(rf, &f.migrationFlags, "", f.K8sVersion)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			err = m.run(&f.preflightFlags, state.PhaseNodePools)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			return nil
+		},
+	}
+
+	f.migrationFlags.add(c)
+	f.preflightFlags.add(c)
+	c.Flags().StringVar(&f.K8sVersion, "k8s-version", "", "Kubernetes version of the new CAPI cluster. Defaults to the version of the GS release of the cluster.")
+
+	return c
+}
+
+func newCreateAllCommand(rf *rootFlags) *cobra.Command {
+	var f createFlags
+
+	c := &cobra.Command{
+		Use:   "all",
+		Short: "Create the control plane, switch the API DNS record and create the node pools.",
+		Long: `Create the control plane, switch the API DNS record and create the node pools.
+
+The progress is recorded in the state file. If a phase fails, fix the problem
+and continue the migration with "resume".`,
+		Args: cobra.NoArgs,
+		PreRunE: func(c *cobra.Command, args []string) error {
+			return f.validate()
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			m, err := newMigration(rf, &f.migrationFlags, f.AWSRegion, f.K8sVersion)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			err = m.run(&f.preflightFlags, state.Phases...)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			return nil
+		},
+	}
+
+	f.migrationFlags.add(c)
+	f.preflightFlags.add(c)
+	c.Flags().StringVar(&f.AWSRegion, "aws-region", "", "AWS Region. Defaults to the region the migration was started with, or the region of the GS cluster.")
+	c.Flags().StringVar(&f.K8sVersion, "k8s-version", "", "Kubernetes version of the new CAPI cluster. Defaults to the version of the GS release of the cluster.")
+
+	return c
+}
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/aws-gs-to-capi/capi"
+	"github.com/giantswarm/aws-gs-to-capi/dns"
+	"github.com/giantswarm/aws-gs-to-capi/state"
+)
+
+type deleteFlags struct {
+	migrationFlags
+
+	AWSRegion string
+}
+
+func newDeleteCommand(rf *rootFlags) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "delete",
+		Short: "Delete CAPI resources and DNS records of the migrated cluster.",
+		Args:  cobra.NoArgs,
+		RunE:  usage,
+	}
+
+	c.AddCommand(newDeleteCPCommand(rf))
+	c.AddCommand(newDeleteNPCommand(rf))
+	c.AddCommand(newDeleteDNSCommand(rf))
+	c.AddCommand(newDeleteAllCommand(rf))
+
+	return c
+}
+
+func newDeleteCPCommand(rf *rootFlags) *cobra.Command {
+	var f deleteFlags
+
+	c := &cobra.Command{
+		Use:   "cp",
+		Short: "Delete the control plane resources and secrets.",
+		Args:  cobra.NoArgs,
+		PreRunE: func(c *cobra.Command, args []string) error {
+			return f.validate()
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			m, err := newMigration(rf, &f.migrationFlags, "", "")
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			err = capi.DeleteCPResources(m.capiCRs, f.Context)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			err = m.reset(state.PhaseControlPlane, state.PhaseControlPlaneScale)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			return nil
+		},
+	}
+
+	f.migrationFlags.add(c)
+
+	return c
+}
+
+func newDeleteNPCommand(rf *rootFlags) *cobra.Command {
+	var f deleteFlags
+
+	c := &cobra.Command{
+		Use:   "np",
+		Short: "Delete the node pool resources.",
+		Args:  cobra.NoArgs,
+		PreRunE: func(c *cobra.Command, args []string) error {
+			return f.validate()
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			m, err := newMigration(rf, &f.migrationFlags, "", "")
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			err = capi.DeleteNPResources(m.capiCRs, f.Context)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			err = m.reset(state.PhaseNodePools)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			return nil
+		},
+	}
+
+	f.migrationFlags.add(c)
+
+	return c
+}
+
+func newDeleteDNSCommand(rf *rootFlags) *cobra.Command {
+	var f deleteFlags
+
+	c := &cobra.Command{
+		Use:   "dns",
+		Short: "Delete the API DNS record pointing to the new ELB.",
+		Args:  cobra.NoArgs,
+		PreRunE: func(c *cobra.Command, args []string) error {
+			return f.validate()
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			m, err := newMigration(rf, &f.migrationFlags, f.AWSRegion, "")
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			err = dns.DeleteDNSRecords(m.capiCRs.Cluster.Name, m.capiCRs.Cluster.Namespace, apiDomain(m.gsCrs, m.capiCRs.Cluster.Name), m.awsRegion(), f.Context, m.capiCRs.APIVersion())
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			err = m.reset(state.PhaseDNS)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			return nil
+		},
+	}
+
+	f.migrationFlags.add(c)
+	c.Flags().StringVar(&f.AWSRegion, "aws-region", "", "AWS Region. Defaults to the region the migration was started with, or the region of the GS cluster.")
+
+	return c
+}
+
+func newDeleteAllCommand(rf *rootFlags) *cobra.Command {
+	var f deleteFlags
+
+	c := &cobra.Command{
+		Use:   "all",
+		Short: "Delete the node pools, the control plane and the API DNS record.",
+		Args:  cobra.NoArgs,
+		PreRunE: func(c *cobra.Command, args []string) error {
+			return f.validate()
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			m, err := newMigration(rf, &f.migrationFlags, f.AWSRegion, "")
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			err = capi.DeleteNPResources(m.capiCRs, f.Context)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+			err = capi.DeleteCPResources(m.capiCRs, f.Context)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+			err = dns.DeleteDNSRecords(m.capiCRs.Cluster.Name, m.capiCRs.Cluster.Namespace, apiDomain(m.gsCrs, m.capiCRs.Cluster.Name), m.awsRegion(), f.Context, m.capiCRs.APIVersion())
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			err = m.reset(state.Phases...)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			return nil
+		},
+	}
+
+	f.migrationFlags.add(c)
+	c.Flags().StringVar(&f.AWSRegion, "aws-region", "", "AWS Region. Defaults to the region the migration was started with, or the region of the GS cluster.")
+
+	return c
+}
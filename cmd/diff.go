@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/aws-gs-to-capi/ctrlclient"
+	"github.com/giantswarm/aws-gs-to-capi/diff"
+)
+
+type diffFlags struct {
+	transformFlags
+
+	Context    string
+	K8sVersion string
+}
+
+func (f *diffFlags) validate() error {
+	err := validateContext(f.Context)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return f.transformFlags.validate()
+}
+
+func newDiffCommand(rf *rootFlags) *cobra.Command {
+	var f diffFlags
+
+	c := &cobra.Command{
+		Use:   "diff",
+		Short: "Compare the generated CAPI objects with the objects on the CAPI management cluster.",
+		Long: `Compare the generated CAPI objects with the objects on the CAPI management cluster.
+
+Every generated object is fetched from the cluster given with --context and
+all fields set by the tool are compared. Fields populated by the API server or
+by controllers are ignored. Values of Secret data are never printed.
+
+The command exits with 0 when all objects are in sync, with 2 when any object
+is missing or has drifted and with 1 on errors.`,
+		Args: cobra.NoArgs,
+		PreRunE: func(c *cobra.Command, args []string) error {
+			return f.validate()
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			_, capiCRs, err := transform(rf, &f.transformFlags, f.K8sVersion)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			ctrl, err := ctrlclient.GetCtrlClient(f.Context)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			// The control plane has the replicas it was grown to so far.
+			capiCRs, err = capiCRs.Applied(f.Context)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			objs, err := capiCRs.Write(capiCRs.Objects())
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			diffs, err := diff.Objects(context.Background(), ctrl, objs)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			err = diff.Print(os.Stdout, diffs)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			if diff.HasDrift(diffs) {
+				return microerror.Maskf(driftDetectedError, "objects of cluster %q differ from the CAPI management cluster", rf.ClusterID)
+			}
+
+			return nil
+		},
+	}
+
+	f.transformFlags.add(c)
+	addContextFlag(c, &f.Context)
+	c.Flags().StringVar(&f.K8sVersion, "k8s-version", "", "Kubernetes version of the new CAPI cluster. Defaults to the version of the GS release of the cluster.")
+
+	return c
+}
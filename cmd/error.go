@@ -0,0 +1,30 @@
+package cmd
+
+import "github.com/giantswarm/microerror"
+
+var invalidFlagError = &microerror.Error{
+	Kind: "invalidFlagError",
+}
+
+// IsInvalidFlag asserts invalidFlagError.
+func IsInvalidFlag(err error) bool {
+	return microerror.Cause(err) == invalidFlagError
+}
+
+var invalidCommandError = &microerror.Error{
+	Kind: "invalidCommandError",
+}
+
+// IsInvalidCommand asserts invalidCommandError.
+func IsInvalidCommand(err error) bool {
+	return microerror.Cause(err) == invalidCommandError
+}
+
+var driftDetectedError = &microerror.Error{
+	Kind: "driftDetectedError",
+}
+
+// IsDriftDetected asserts driftDetectedError.
+func IsDriftDetected(err error) bool {
+	return microerror.Cause(err) == driftDetectedError
+}
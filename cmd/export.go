@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/aws-gs-to-capi/giantswarm"
+)
+
+type exportFlags struct {
+	sourceFlags
+
+	AgeRecipients []string
+	Output        string
+}
+
+func (f *exportFlags) validate() error {
+	if f.Output == "" {
+		return microerror.Maskf(invalidFlagError, "--output must not be empty")
+	}
+	if len(f.AgeRecipients) == 0 {
+		return microerror.Maskf(invalidFlagError, "--age-recipient must not be empty")
+	}
+
+	return f.sourceFlags.validate()
+}
+
+func newExportCommand(rf *rootFlags) *cobra.Command {
+	var f exportFlags
+
+	c := &cobra.Command{
+		Use:   "export",
+		Short: "Export the GS cluster into an encrypted bundle.",
+		Long: `Export everything needed to transform the GS cluster into a single bundle.
+
+The bundle contains the GS CRs and secrets of the cluster, the CA private key
+from Vault and the network discovered in AWS. It is encrypted with age to the
+given --age-recipient keys.
+
+The other commands read the bundle instead of the old management cluster,
+Vault and AWS when it is given with --source-bundle, e.g. to prepare and
+review a migration ahead of the maintenance window.`,
+		Args: cobra.NoArgs,
+		PreRunE: func(c *cobra.Command, args []string) error {
+			return f.validate()
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			gsCrs, err := fetch(rf, &f.sourceFlags)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			err = giantswarm.WriteBundle(f.Output, gsCrs, f.AgeRecipients)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+			fmt.Printf("Exported cluster %s to %s\n", rf.ClusterID, f.Output)
+
+			return nil
+		},
+	}
+
+	f.sourceFlags.add(c)
+	c.Flags().StringSliceVar(&f.AgeRecipients, "age-recipient", nil, "age public key to encrypt the bundle for. Can be given multiple times.")
+	c.Flags().StringVarP(&f.Output, "output", "o", "", "File to write the bundle to.")
+
+	return c
+}
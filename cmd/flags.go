@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/aws-gs-to-capi/capi"
+)
+
+// sourceFlags select the source the GS cluster is read from. They are used
+// by all commands reading the GS cluster.
+type sourceFlags struct {
+	AgeIdentityFile          string
+	SourceAPIEndpoint        string
+	SourceAPIToken           string
+	SourceBundle             string
+	SourceClusterDomain      string
+	SourceContext            string
+	SourceKubeconfig         string
+	SourceMasterInstanceType string
+	SourcePodsCIDR           string
+	SourceSecretsDir         string
+	SourceServiceCIDR        string
+	WorkloadContext          string
+	WorkloadKubeconfig       string
+}
+
+func (f *sourceFlags) add(c *cobra.Command) {
+	addAgeIdentityFileFlag(c, &f.AgeIdentityFile)
+	c.Flags().StringVar(&f.SourceAPIEndpoint, "source-api-endpoint", "", "GS REST API endpoint to read the GS cluster from instead of the GS management cluster.")
+	c.Flags().StringVar(&f.SourceAPIToken, "source-api-token", os.Getenv("GS_API_TOKEN"), "Auth token for --source-api-endpoint. Defaults to $GS_API_TOKEN.")
+	c.Flags().StringVar(&f.SourceBundle, "source-bundle", "", "Bundle written by export to read the GS cluster from instead of the GS management cluster.")
+	c.Flags().StringVar(&f.SourceClusterDomain, "source-cluster-domain", "", "Cluster domain of the cluster. Required when the source does not provide it, e.g. with --source-api-endpoint, cross-checked otherwise.")
+	c.Flags().StringVar(&f.SourceContext, "source-context", "", "k8s context of the GS management cluster. Defaults to the current context.")
+	c.Flags().StringVar(&f.SourceKubeconfig, "source-kubeconfig", "", "kubeconfig of the GS management cluster. Defaults to $KUBECONFIG or $HOME/.kube/config.")
+	c.Flags().StringVar(&f.SourceMasterInstanceType, "source-master-instance-type", "", "Instance type of the masters of the cluster, required with --source-api-endpoint.")
+	c.Flags().StringVar(&f.SourcePodsCIDR, "source-pods-cidr", "", "Pod CIDR of the cluster, required with --source-api-endpoint.")
+	c.Flags().StringVar(&f.SourceSecretsDir, "source-secrets-dir", "", "Directory with the Secret manifests of the cluster, required with --source-api-endpoint.")
+	c.Flags().StringVar(&f.SourceServiceCIDR, "source-service-cidr", "", "Service CIDR of the cluster. Required when the source does not provide it, e.g. with --source-api-endpoint, cross-checked otherwise.")
+	c.Flags().StringVar(&f.WorkloadContext, "workload-context", "", "k8s context of the GS workload cluster in --workload-kubeconfig. Defaults to the current context.")
+	c.Flags().StringVar(&f.WorkloadKubeconfig, "workload-kubeconfig", "", "kubeconfig of the GS workload cluster. When given, the service CIDR and cluster domain are read from the running cluster and cross-checked with the source, and the labels and taints of the nodes are kept.")
+}
+
+func (f *sourceFlags) validate() error {
+	if f.SourceBundle != "" && f.SourceAPIEndpoint != "" {
+		return microerror.Maskf(invalidFlagError, "--source-bundle and --source-api-endpoint must not be given together")
+	}
+	if f.SourceAPIEndpoint != "" && f.SourceSecretsDir == "" {
+		return microerror.Maskf(invalidFlagError, "--source-secrets-dir must not be empty when --source-api-endpoint is given")
+	}
+	if f.SourceAPIEndpoint != "" && f.SourcePodsCIDR == "" {
+		return microerror.Maskf(invalidFlagError, "--source-pods-cidr must not be empty when --source-api-endpoint is given")
+	}
+	if f.SourceAPIEndpoint != "" && f.SourceMasterInstanceType == "" {
+		return microerror.Maskf(invalidFlagError, "--source-master-instance-type must not be empty when --source-api-endpoint is given")
+	}
+
+	return nil
+}
+
+// transformFlags configure how the GS cluster is transformed into the CAPI
+// objects. They are used by all commands generating the CAPI objects.
+type transformFlags struct {
+	sourceFlags
+
+	CAPIAPIVersion              string
+	ClusterClass                string
+	ControlPlaneInstanceProfile string
+	NodeInstanceProfile         string
+	NodePoolInstanceProfiles    map[string]string
+	NodePoolLabels              []string
+	NodePoolMode                string
+	NodePoolModes               map[string]string
+	NodePoolTaints              []string
+	NoSSHKey                    bool
+	PropagateAnnotations        []string
+	PropagateLabels             []string
+	SSHKeyName                  string
+	SSM                         bool
+	TargetNamespace             string
+	WorkerClass                 string
+}
+
+func (f *transformFlags) add(c *cobra.Command) {
+	f.sourceFlags.add(c)
+
+	c.Flags().StringVar(&f.CAPIAPIVersion, "capi-api-version", capi.APIVersionV1alpha3, fmt.Sprintf("CAPI API version of the objects on the CAPI management cluster, one of %v.", capi.APIVersions))
+	c.Flags().StringVar(&f.ClusterClass, "cluster-class", "", "ClusterClass to create the cluster from. Creates a Cluster with spec.topology instead of the single objects, requires --capi-api-version=v1beta1.")
+	c.Flags().StringVar(&f.ControlPlaneInstanceProfile, "control-plane-instance-profile", capi.DefaultControlPlaneInstanceProfile, "IAM instance profile of the control plane machines.")
+	c.Flags().StringVar(&f.NodeInstanceProfile, "node-instance-profile", capi.DefaultNodeInstanceProfile, "IAM instance profile of the workers.")
+	c.Flags().StringToStringVar(&f.NodePoolInstanceProfiles, "node-pool-instance-profile", nil, "IAM instance profile of the workers of single node pools, e.g. np001=my-profile.")
+	c.Flags().StringArrayVar(&f.NodePoolLabels, "node-pool-label", nil, "Node label of the workers of a node pool added to the ones of the GS node pool, e.g. np001:team=data. Can be given multiple times.")
+	c.Flags().StringVar(&f.NodePoolMode, "node-pool-mode", "", fmt.Sprintf("How node pools are created, one of %v. machinedeployment does not need the MachinePool feature gate. Defaults to machinepool, or machinedeployment with --cluster-class.", capi.NodePoolModes))
+	c.Flags().StringToStringVar(&f.NodePoolModes, "node-pool-mode-override", nil, "Node pool mode of single node pools, e.g. np001=machinedeployment.")
+	c.Flags().StringArrayVar(&f.NodePoolTaints, "node-pool-taint", nil, "Node taint of the workers of a node pool added to the ones of the GS node pool, e.g. np001:dedicated=data:NoSchedule. Can be given multiple times.")
+	c.Flags().BoolVar(&f.NoSSHKey, "no-ssh-key", false, "Create the machines without EC2 key pair.")
+	c.Flags().StringSliceVar(&f.PropagateAnnotations, "propagate-annotations", nil, "Key prefixes of the annotations of the GS CRs which are copied to the CAPI objects. The descriptions of the cluster and node pools are always copied.")
+	c.Flags().StringSliceVar(&f.PropagateLabels, "propagate-labels", capi.DefaultLabelPrefixes, "Key prefixes of the labels of the GS CRs which are copied to the CAPI objects. Empty copies no labels.")
+	c.Flags().StringVar(&f.SSHKeyName, "ssh-key-name", "", "EC2 key pair of all machines. Defaults to the default key pair of CAPA.")
+	c.Flags().BoolVar(&f.SSM, "ssm", false, "Install the SSM agent on all machines, so that they can be accessed with AWS Session Manager.")
+	c.Flags().StringVar(&f.TargetNamespace, "target-namespace", "", "Namespace of the CAPI resources on the CAPI management cluster. Defaults to the namespace of the GS cluster CRs.")
+	c.Flags().StringVar(&f.WorkerClass, "worker-class", "", "MachineDeployment class of --cluster-class the node pools are created with. Defaults to default-worker.")
+}
+
+func (f *transformFlags) validate() error {
+	if !contains(capi.APIVersions, f.CAPIAPIVersion) {
+		return microerror.Maskf(invalidFlagError, "--capi-api-version must be one of %v", capi.APIVersions)
+	}
+	if f.NodePoolMode != "" && !contains(capi.NodePoolModes, f.NodePoolMode) {
+		return microerror.Maskf(invalidFlagError, "--node-pool-mode must be one of %v", capi.NodePoolModes)
+	}
+	for id, mode := range f.NodePoolModes {
+		if !contains(capi.NodePoolModes, mode) {
+			return microerror.Maskf(invalidFlagError, "--node-pool-mode-override of node pool %s must be one of %v", id, capi.NodePoolModes)
+		}
+	}
+	if f.ClusterClass != "" {
+		if f.CAPIAPIVersion != capi.APIVersionV1beta1 {
+			return microerror.Maskf(invalidFlagError, "--cluster-class requires --capi-api-version=%s", capi.APIVersionV1beta1)
+		}
+		if f.NodePoolMode == capi.NodePoolModeMachinePool || contains(values(f.NodePoolModes), capi.NodePoolModeMachinePool) {
+			return microerror.Maskf(invalidFlagError, "--cluster-class does not support the node pool mode %q", capi.NodePoolModeMachinePool)
+		}
+	}
+	if f.WorkerClass != "" && f.ClusterClass == "" {
+		return microerror.Maskf(invalidFlagError, "--worker-class requires --cluster-class")
+	}
+	if f.SSHKeyName != "" && f.NoSSHKey {
+		return microerror.Maskf(invalidFlagError, "--ssh-key-name and --no-ssh-key must not be given together")
+	}
+
+	return f.sourceFlags.validate()
+}
+
+// migrationFlags are used by all commands running or reverting phases of the
+// migration on the CAPI management cluster.
+type migrationFlags struct {
+	transformFlags
+
+	Context   string
+	StateFile string
+}
+
+func (f *migrationFlags) add(c *cobra.Command) {
+	f.transformFlags.add(c)
+
+	addContextFlag(c, &f.Context)
+	c.Flags().StringVar(&f.StateFile, "state-file", "", "File to record the migration progress in. Defaults to <cluster-id>.migration.yaml.")
+}
+
+func (f *migrationFlags) validate() error {
+	err := validateContext(f.Context)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return f.transformFlags.validate()
+}
+
+// preflightFlags configure the checks of the AWS resources, which run before
+// any object is created.
+type preflightFlags struct {
+	ControlPlaneInstanceProfilePolicies []string
+	NodeInstanceProfilePolicies         []string
+	SkipPreflight                       bool
+}
+
+func (f *preflightFlags) add(c *cobra.Command) {
+	c.Flags().StringSliceVar(&f.ControlPlaneInstanceProfilePolicies, "control-plane-instance-profile-policies", []string{capi.DefaultControlPlaneInstanceProfile, capi.DefaultNodeInstanceProfile}, "IAM policies the role of --control-plane-instance-profile must have.")
+	c.Flags().StringSliceVar(&f.NodeInstanceProfilePolicies, "node-instance-profile-policies", []string{capi.DefaultNodeInstanceProfile}, "IAM policies the roles of the worker instance profiles must have.")
+	c.Flags().BoolVar(&f.SkipPreflight, "skip-preflight", false, "Skip checking the AWS resources referenced by the CAPI objects, e.g. when working from a bundle without AWS credentials.")
+}
+
+func addAgeIdentityFileFlag(c *cobra.Command, path *string) {
+	c.Flags().StringVar(path, "age-identity-file", defaultAgeIdentityFile(), "File with the age identities to decrypt bundles and Secrets with.")
+}
+
+func addContextFlag(c *cobra.Command, context *string) {
+	c.Flags().StringVar(context, "context", "", "define in which k8s context the resources should be created")
+}
+
+func validateContext(context string) error {
+	if context == "" {
+		return microerror.Maskf(invalidFlagError, "--context must not be empty")
+	}
+
+	return nil
+}
@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/giantswarm/microerror"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/giantswarm/aws-gs-to-capi/capi"
+	"github.com/giantswarm/aws-gs-to-capi/dns"
+	"github.com/giantswarm/aws-gs-to-capi/giantswarm"
+	"github.com/giantswarm/aws-gs-to-capi/state"
+)
+
+// migration executes the phases of a cluster migration and records their
+// progress in the state file.
+type migration struct {
+	flags     *migrationFlags
+	statePath string
+	state     *state.Migration
+
+	gsCrs   *giantswarm.GSClusterCrs
+	capiCRs *capi.Crs
+}
+
+func loadState(rf *rootFlags, statePath string) (*state.Migration, string, error) {
+	if statePath == "" {
+		statePath = state.DefaultPath(rf.ClusterID)
+	}
+
+	s, err := state.Load(statePath, rf.ClusterID)
+	if err != nil {
+		return nil, "", microerror.Mask(err)
+	}
+
+	return s, statePath, nil
+}
+
+// newMigration loads the state of the migration and transforms the GS CRs.
+// Non-empty values of awsRegion and k8sVersion are recorded in the state, as
+// well as the Kubernetes version resolved from the GS release.
+func newMigration(rf *rootFlags, f *migrationFlags, awsRegion string, k8sVersion string) (*migration, error) {
+	s, statePath, err := loadState(rf, f.StateFile)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	s.Context = f.Context
+	if awsRegion != "" {
+		s.AWSRegion = awsRegion
+	}
+	if k8sVersion != "" {
+		s.K8sVersion = k8sVersion
+	}
+
+	gsCrs, capiCRs, err := transform(rf, &f.transformFlags, s.K8sVersion)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	// The version resolved from the GS release is recorded, so that resuming
+	// the migration uses the same version even if the release changes.
+	s.K8sVersion = capiCRs.Versions.Kubernetes
+
+	m := &migration{
+		flags:     f,
+		statePath: statePath,
+		state:     s,
+
+		gsCrs:   gsCrs,
+		capiCRs: capiCRs,
+	}
+
+	return m, nil
+}
+
+// run executes the given phases in order. Every phase is executed even when
+// it has been completed before. The preflight checks run before any object
+// is created, unless they are skipped. pf is nil for commands which do not
+// create objects.
+func (m *migration) run(pf *preflightFlags, phases ...string) error {
+	if pf != nil && !pf.SkipPreflight && createsObjects(phases) {
+		err := runPreflight(pf, &m.flags.transformFlags, m.gsCrs, m.capiCRs)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	for _, p := range phases {
+		fmt.Printf("Running phase %q\n", p)
+
+		objs, err := m.runPhase(p)
+		if err != nil {
+			m.state.Fail(p, err)
+			saveErr := m.state.Save(m.statePath)
+			if saveErr != nil {
+				fmt.Printf("failed to save migration state to %s: %s\n", m.statePath, saveErr)
+			}
+			return microerror.Mask(err)
+		}
+
+		err = m.state.Complete(p, objs)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		err = m.state.Save(m.statePath)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		fmt.Printf("Completed phase %q, recorded in %s\n", p, m.statePath)
+	}
+
+	return nil
+}
+
+func (m *migration) runPhase(phase string) ([]runtime.Object, error) {
+	switch phase {
+	case state.PhaseControlPlane:
+		err := capi.ApplyControlPlaneResources(m.capiCRs, m.flags.Context)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		return m.capiCRs.ControlPlaneObjects(), nil
+
+	case state.PhaseControlPlaneScale:
+		err := capi.ScaleControlPlane(m.capiCRs, m.flags.Context)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		return nil, nil
+
+	case state.PhaseDNS:
+		err := m.updateDNS()
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		return nil, nil
+
+	case state.PhaseNodePools:
+		err := capi.ApplyNodePoolResources(m.capiCRs, m.flags.Context)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		return m.capiCRs.NodePoolObjects(), nil
+	}
+
+	return nil, microerror.Maskf(invalidCommandError, "unknown migration phase %q", phase)
+}
+
+// updateDNS records the original target of the API DNS record before
+// switching it to the new ELB, so that it can be restored by hand.
+func (m *migration) updateDNS() error {
+	m.state.AWSRegion = m.awsRegion()
+	domain := apiDomain(m.gsCrs, m.capiCRs.Cluster.Name)
+
+	if m.state.OriginalDNSTarget == "" {
+		target, err := dns.GetAPIRecordTarget(domain, m.state.AWSRegion)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		m.state.OriginalDNSTarget = target
+		err = m.state.Save(m.statePath)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	err := dns.UpdateAPIDNSToNewELB(m.capiCRs.Cluster.Name, m.capiCRs.Cluster.Namespace, domain, m.state.AWSRegion, m.flags.Context, m.capiCRs.APIVersion())
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// awsRegion returns the region the migration was started with, or the region
+// of the GS cluster when it is not recorded.
+func (m *migration) awsRegion() string {
+	return awsRegion(m.state.AWSRegion, m.gsCrs)
+}
+
+// awsRegion returns region, or the region of the GS cluster when it is empty.
+func awsRegion(region string, gsCrs *giantswarm.GSClusterCrs) string {
+	if region != "" {
+		return region
+	}
+	if r := gsCrs.AWSCluster.Spec.Provider.Region; r != "" {
+		return r
+	}
+
+	return defaultAWSRegion
+}
+
+// reset removes the phases from the state file after their resources have
+// been deleted. Nothing is recorded when there is no state file.
+func (m *migration) reset(phases ...string) error {
+	if !state.Exists(m.statePath) {
+		return nil
+	}
+
+	for _, p := range phases {
+		m.state.Reset(p)
+	}
+
+	err := m.state.Save(m.statePath)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func createsObjects(phases []string) bool {
+	for _, p := range phases {
+		if p == state.PhaseControlPlane || p == state.PhaseNodePools {
+			return true
+		}
+	}
+
+	return false
+}
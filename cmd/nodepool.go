@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	v1 "k8s.io/api/core/v1"
+)
+
+// nodePoolLabels parses the --node-pool-label flags, given as
+// <node-pool-id>:<key>=<value>, keyed by node pool ID.
+func nodePoolLabels(flags []string) (map[string]map[string]string, error) {
+	labels := map[string]map[string]string{}
+	for _, flag := range flags {
+		id, label := splitNodePool(flag)
+		kv := strings.SplitN(label, "=", 2)
+		if id == "" || kv[0] == "" || len(kv) != 2 {
+			return nil, microerror.Maskf(invalidFlagError, "--node-pool-label must be <node-pool-id>:<key>=<value> but got %q", flag)
+		}
+
+		if labels[id] == nil {
+			labels[id] = map[string]string{}
+		}
+		labels[id][kv[0]] = kv[1]
+	}
+
+	return labels, nil
+}
+
+// nodePoolTaints parses the --node-pool-taint flags, given as
+// <node-pool-id>:<key>[=<value>]:<effect>, keyed by node pool ID.
+func nodePoolTaints(flags []string) (map[string][]v1.Taint, error) {
+	effects := []string{
+		string(v1.TaintEffectNoSchedule),
+		string(v1.TaintEffectPreferNoSchedule),
+		string(v1.TaintEffectNoExecute),
+	}
+
+	taints := map[string][]v1.Taint{}
+	for _, flag := range flags {
+		id, taint := splitNodePool(flag)
+		i := strings.LastIndex(taint, ":")
+		if id == "" || i < 1 {
+			return nil, microerror.Maskf(invalidFlagError, "--node-pool-taint must be <node-pool-id>:<key>[=<value>]:<effect> but got %q", flag)
+		}
+		effect := taint[i+1:]
+		if !contains(effects, effect) {
+			return nil, microerror.Maskf(invalidFlagError, "effect of --node-pool-taint %q must be one of %v", flag, effects)
+		}
+		kv := strings.SplitN(taint[:i], "=", 2)
+		if kv[0] == "" {
+			return nil, microerror.Maskf(invalidFlagError, "--node-pool-taint must be <node-pool-id>:<key>[=<value>]:<effect> but got %q", flag)
+		}
+
+		t := v1.Taint{
+			Key:    kv[0],
+			Effect: v1.TaintEffect(effect),
+		}
+		if len(kv) == 2 {
+			t.Value = kv[1]
+		}
+		taints[id] = append(taints[id], t)
+	}
+
+	return taints, nil
+}
+
+// splitNodePool splits the node pool ID off the value of a node pool flag.
+func splitNodePool(flag string) (string, string) {
+	i := strings.Index(flag, ":")
+	if i < 0 {
+		return "", flag
+	}
+
+	return flag[:i], flag[i+1:]
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func Test_nodePoolLabels(t *testing.T) {
+	testCases := []struct {
+		name     string
+		flags    []string
+		expected map[string]map[string]string
+	}{
+		{
+			name:     "case 0: no flags",
+			expected: map[string]map[string]string{},
+		},
+		{
+			name:  "case 1: labels of several node pools",
+			flags: []string{"np001:team=data", "np002:team=web", "np001:tier=backend"},
+			expected: map[string]map[string]string{
+				"np001": {"team": "data", "tier": "backend"},
+				"np002": {"team": "web"},
+			},
+		},
+		{
+			name:  "case 2: empty value",
+			flags: []string{"np001:dedicated="},
+			expected: map[string]map[string]string{
+				"np001": {"dedicated": ""},
+			},
+		},
+		{
+			name:  "case 3: value containing : and =",
+			flags: []string{"np001:example.com/selector=a=b:c"},
+			expected: map[string]map[string]string{
+				"np001": {"example.com/selector": "a=b:c"},
+			},
+		},
+		{
+			name:  "case 4: later flag wins",
+			flags: []string{"np001:team=data", "np001:team=web"},
+			expected: map[string]map[string]string{
+				"np001": {"team": "web"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			labels, err := nodePoolLabels(tc.flags)
+			if err != nil {
+				t.Fatalf("nodePoolLabels() error = %v", err)
+			}
+			if !reflect.DeepEqual(labels, tc.expected) {
+				t.Fatalf("nodePoolLabels() = %v, want %v", labels, tc.expected)
+			}
+		})
+	}
+}
+
+func Test_nodePoolLabels_Errors(t *testing.T) {
+	testCases := []struct {
+		name string
+		flag string
+	}{
+		{name: "case 0: no node pool", flag: "team=data"},
+		{name: "case 1: empty node pool", flag: ":team=data"},
+		{name: "case 2: no value", flag: "np001:team"},
+		{name: "case 3: empty key", flag: "np001:=data"},
+		{name: "case 4: empty label", flag: "np001:"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := nodePoolLabels([]string{"np001:team=data", tc.flag})
+			if !IsInvalidFlag(err) {
+				t.Fatalf("nodePoolLabels() error = %v, want invalid flag error", err)
+			}
+		})
+	}
+}
+
+func Test_nodePoolTaints(t *testing.T) {
+	testCases := []struct {
+		name     string
+		flags    []string
+		expected map[string][]v1.Taint
+	}{
+		{
+			name:     "case 0: no flags",
+			expected: map[string][]v1.Taint{},
+		},
+		{
+			name:  "case 1: taint with value",
+			flags: []string{"np001:dedicated=data:NoSchedule"},
+			expected: map[string][]v1.Taint{
+				"np001": {{Key: "dedicated", Value: "data", Effect: v1.TaintEffectNoSchedule}},
+			},
+		},
+		{
+			name:  "case 2: taint without value",
+			flags: []string{"np001:dedicated:PreferNoSchedule"},
+			expected: map[string][]v1.Taint{
+				"np001": {{Key: "dedicated", Effect: v1.TaintEffectPreferNoSchedule}},
+			},
+		},
+		{
+			name:  "case 3: empty value",
+			flags: []string{"np001:dedicated=:NoExecute"},
+			expected: map[string][]v1.Taint{
+				"np001": {{Key: "dedicated", Effect: v1.TaintEffectNoExecute}},
+			},
+		},
+		{
+			name:  "case 4: value containing : and =",
+			flags: []string{"np001:example.com/dedicated=a=b:c:NoSchedule"},
+			expected: map[string][]v1.Taint{
+				"np001": {{Key: "example.com/dedicated", Value: "a=b:c", Effect: v1.TaintEffectNoSchedule}},
+			},
+		},
+		{
+			name:  "case 5: taints of several node pools in order",
+			flags: []string{"np001:a=1:NoSchedule", "np002:b:NoExecute", "np001:a=1:NoExecute"},
+			expected: map[string][]v1.Taint{
+				"np001": {
+					{Key: "a", Value: "1", Effect: v1.TaintEffectNoSchedule},
+					{Key: "a", Value: "1", Effect: v1.TaintEffectNoExecute},
+				},
+				"np002": {{Key: "b", Effect: v1.TaintEffectNoExecute}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			taints, err := nodePoolTaints(tc.flags)
+			if err != nil {
+				t.Fatalf("nodePoolTaints() error = %v", err)
+			}
+			if !reflect.DeepEqual(taints, tc.expected) {
+				t.Fatalf("nodePoolTaints() = %v, want %v", taints, tc.expected)
+			}
+		})
+	}
+}
+
+func Test_nodePoolTaints_Errors(t *testing.T) {
+	testCases := []struct {
+		name string
+		flag string
+	}{
+		{name: "case 0: no node pool", flag: "dedicated=data"},
+		{name: "case 1: empty node pool", flag: ":dedicated=data:NoSchedule"},
+		{name: "case 2: no effect", flag: "np001:dedicated=data"},
+		{name: "case 3: empty effect", flag: "np001:dedicated=data:"},
+		{name: "case 4: invalid effect", flag: "np001:dedicated=data:NoRun"},
+		{name: "case 5: effect in lower case", flag: "np001:dedicated=data:noschedule"},
+		{name: "case 6: empty key", flag: "np001::NoSchedule"},
+		{name: "case 7: empty key with value", flag: "np001:=data:NoSchedule"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := nodePoolTaints([]string{"np001:dedicated=data:NoSchedule", tc.flag})
+			if !IsInvalidFlag(err) {
+				t.Fatalf("nodePoolTaints() error = %v, want invalid flag error", err)
+			}
+		})
+	}
+}
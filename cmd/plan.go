@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/aws-gs-to-capi/dns"
+	"github.com/giantswarm/aws-gs-to-capi/plan"
+	"github.com/giantswarm/aws-gs-to-capi/state"
+)
+
+type planFlags struct {
+	transformFlags
+
+	AWSRegion  string
+	K8sVersion string
+	StateFile  string
+}
+
+func newPlanCommand(rf *rootFlags) *cobra.Command {
+	var f planFlags
+
+	c := &cobra.Command{
+		Use:   "plan",
+		Short: "Show the full migration plan without changing anything.",
+		Long: `Show the full migration plan without changing anything.
+
+The GS CRs are fetched and the AWS resources of the cluster are discovered.
+The plan lists, in order, every Kubernetes object which would be applied,
+every Route53 change which would be made and every step which still has to be
+done by hand.
+
+When the migration has been started, the phases completed according to the
+state file are marked, since "resume" skips them.`,
+		Args: cobra.NoArgs,
+		PreRunE: func(c *cobra.Command, args []string) error {
+			return f.validate()
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			s, statePath, err := loadState(rf, f.StateFile)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+			if !state.Exists(statePath) {
+				s = nil
+			}
+
+			gsCrs, capiCRs, err := transform(rf, &f.transformFlags, f.K8sVersion)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			domain := apiDomain(gsCrs, capiCRs.Cluster.Name)
+
+			target, err := dns.GetAPIRecordTarget(domain, awsRegion(f.AWSRegion, gsCrs))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to look up the current API DNS record: %s\n", err)
+			}
+
+			p, err := plan.New(plan.Config{
+				Crs:              capiCRs,
+				APIDomain:        domain,
+				CurrentDNSTarget: target,
+				SourceAPIVersion: gsCrs.APIVersion,
+				State:            s,
+			})
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			err = p.Print(os.Stdout)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			return nil
+		},
+	}
+
+	f.transformFlags.add(c)
+	c.Flags().StringVar(&f.AWSRegion, "aws-region", "", "AWS Region. Defaults to the region of the GS cluster.")
+	c.Flags().StringVar(&f.K8sVersion, "k8s-version", "", "Kubernetes version of the new CAPI cluster. Defaults to the version of the GS release of the cluster.")
+	c.Flags().StringVar(&f.StateFile, "state-file", "", "File the migration progress is recorded in. Defaults to <cluster-id>.migration.yaml.")
+
+	return c
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/aws-gs-to-capi/capi"
+	"github.com/giantswarm/aws-gs-to-capi/giantswarm"
+	"github.com/giantswarm/aws-gs-to-capi/render"
+)
+
+const (
+	layoutFlat   = "flat"
+	layoutGitOps = "gitops"
+	layoutValues = "values"
+)
+
+type renderFlags struct {
+	transformFlags
+
+	AgeRecipients  []string
+	K8sVersion     string
+	Layout         string
+	OutputDir      string
+	ValuesTemplate string
+}
+
+func (f *renderFlags) validate() error {
+	switch f.Layout {
+	case layoutFlat:
+	case layoutGitOps:
+		if f.OutputDir == "" {
+			return microerror.Maskf(invalidFlagError, "--output-dir must not be empty for --layout=%s", layoutGitOps)
+		}
+	case layoutValues:
+	default:
+		return microerror.Maskf(invalidFlagError, "--layout must be one of %q, %q or %q", layoutFlat, layoutGitOps, layoutValues)
+	}
+	if f.ValuesTemplate != "" && f.Layout != layoutValues {
+		return microerror.Maskf(invalidFlagError, "--values-template requires --layout=%s", layoutValues)
+	}
+	if len(f.AgeRecipients) > 0 && f.OutputDir == "" {
+		return microerror.Maskf(invalidFlagError, "--output-dir must not be empty when --age-recipient is given")
+	}
+
+	return f.transformFlags.validate()
+}
+
+func newRenderCommand(rf *rootFlags) *cobra.Command {
+	var f renderFlags
+
+	c := &cobra.Command{
+		Use:   "render",
+		Short: "Write the generated CAPI manifests instead of creating them.",
+		Long: `Write the generated CAPI manifests instead of creating them.
+
+With --layout=flat and without --output-dir all objects are written to stdout
+as one multi-document YAML stream. With --output-dir every object is written
+into its own file.
+
+With --layout=gitops a kustomize directory tree is written to
+<output-dir>/<cluster-id>, which can be reconciled by Flux:
+
+    control-plane/            Cluster, AWSCluster, KubeadmControlPlane, AWSMachineTemplate
+    node-pools/<node-pool>/   AWSMachinePool, KubeadmConfig, MachinePool, or with
+                              --node-pool-mode=machinedeployment AWSMachineTemplate,
+                              KubeadmConfigTemplate, MachineDeployment per subnet
+    secrets/                  custom files, etcd, service account and CA secrets
+
+With --cluster-class the control-plane directory only contains the Cluster
+with spec.topology including the node pools, there are no node-pools
+directories.
+
+With --layout=values a values.yaml for a cluster chart (cluster-aws style) is
+written instead of the CAPI objects, to stdout or to <output-dir>/values.yaml.
+With --output-dir the Secrets referenced by the values are written to
+<output-dir>/secrets. --values-template replaces the built-in template with a
+Go template file, which gets the render.Values as data and can use the quote
+and join functions.
+
+The control plane is rendered with a single replica in all layouts, since
+every new machine joins the etcd cluster of the GS cluster. After the first
+machine is ready, grow the control plane with "update cp", which adds one
+replica at a time.
+
+The output contains the cluster secrets including the CA private key. With
+--age-recipient the data of all Secrets is encrypted in the SOPS format, so
+the output can be committed and decrypted by sops or Flux.`,
+		Args: cobra.NoArgs,
+		PreRunE: func(c *cobra.Command, args []string) error {
+			return f.validate()
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			r, err := render.New(render.Config{
+				AgeRecipients: f.AgeRecipients,
+			})
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			gsCRs, capiCRs, err := transform(rf, &f.transformFlags, f.K8sVersion)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+			// The control plane is created with a single replica, it is
+			// grown with "update cp" afterwards.
+			capiCRs, err = capiCRs.Initial()
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			if f.Layout == layoutValues {
+				return renderValues(r, f, gsCRs, capiCRs)
+			}
+
+			objs, err := capiCRs.Write(capiCRs.Objects())
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			switch {
+			case f.Layout == layoutGitOps:
+				err = r.WriteGitOps(f.OutputDir, capiCRs)
+			case f.OutputDir != "":
+				err = r.WriteDir(f.OutputDir, objs)
+			default:
+				err = r.WriteStream(os.Stdout, objs)
+			}
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			if f.OutputDir != "" {
+				fmt.Fprintf(os.Stderr, "Rendered manifests to %s\n", f.OutputDir)
+			}
+
+			return nil
+		},
+	}
+
+	f.transformFlags.add(c)
+	c.Flags().StringSliceVar(&f.AgeRecipients, "age-recipient", nil, "age public key to encrypt the data of Secrets for. Can be given multiple times.")
+	c.Flags().StringVar(&f.K8sVersion, "k8s-version", "", "Kubernetes version of the new CAPI cluster. Defaults to the version of the GS release of the cluster.")
+	c.Flags().StringVar(&f.Layout, "layout", layoutFlat, fmt.Sprintf("Output layout, one of %q, %q or %q.", layoutFlat, layoutGitOps, layoutValues))
+	c.Flags().StringVar(&f.OutputDir, "output-dir", "", "Directory to write one file per object to. Defaults to a single YAML stream on stdout.")
+	c.Flags().StringVar(&f.ValuesTemplate, "values-template", "", "Go template file to render the values of --layout=values with. Defaults to the built-in cluster-aws template.")
+
+	return c
+}
+
+func renderValues(r *render.Renderer, f renderFlags, gsCRs *giantswarm.GSClusterCrs, capiCRs *capi.Crs) error {
+	tmpl, err := render.ReadValuesTemplate(f.ValuesTemplate)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	values := render.NewValues(gsCRs, capiCRs)
+
+	if f.OutputDir == "" {
+		err = r.WriteValues(os.Stdout, tmpl, values)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		return nil
+	}
+
+	err = r.WriteValuesDir(f.OutputDir, tmpl, values, capiCRs)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Rendered values to %s\n", f.OutputDir)
+
+	return nil
+}
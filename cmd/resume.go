@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/aws-gs-to-capi/state"
+)
+
+type resumeFlags struct {
+	migrationFlags
+	preflightFlags
+
+	AWSRegion  string
+	K8sVersion string
+}
+
+func newResumeCommand(rf *rootFlags) *cobra.Command {
+	var f resumeFlags
+
+	c := &cobra.Command{
+		Use:   "resume",
+		Short: "Continue a migration from the last successful phase.",
+		Long: `Continue a migration from the last successful phase.
+
+The completed phases are read from the state file and skipped. The AWS region
+and Kubernetes version the migration was started with are used unless they
+are given as flags.`,
+		Args: cobra.NoArgs,
+		PreRunE: func(c *cobra.Command, args []string) error {
+			return f.validate()
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			s, statePath, err := loadState(rf, f.StateFile)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+			if !state.Exists(statePath) {
+				return microerror.Maskf(invalidFlagError, "state file %s does not exist, start the migration with \"create all\"", statePath)
+			}
+
+			remaining := s.Remaining()
+			if len(remaining) == 0 {
+				fmt.Printf("Migration of cluster %q is already completed\n", rf.ClusterID)
+				return nil
+			}
+			if s.LastFailure != nil {
+				fmt.Printf("Last run failed in phase %q at %s: %s\n", s.LastFailure.Phase, s.LastFailure.FailedAt, s.LastFailure.Error)
+			}
+
+			m, err := newMigration(rf, &f.migrationFlags, f.AWSRegion, f.K8sVersion)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			err = m.run(&f.preflightFlags, remaining...)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			return nil
+		},
+	}
+
+	f.migrationFlags.add(c)
+	f.preflightFlags.add(c)
+	c.Flags().StringVar(&f.AWSRegion, "aws-region", "", "AWS Region. Defaults to the region the migration was started with.")
+	c.Flags().StringVar(&f.K8sVersion, "k8s-version", "", "Kubernetes version of the new CAPI cluster. Defaults to the version the migration was started with.")
+
+	return c
+}
@@ -0,0 +1,366 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"filippo.io/age"
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/aws-gs-to-capi/capi"
+	"github.com/giantswarm/aws-gs-to-capi/giantswarm"
+	"github.com/giantswarm/aws-gs-to-capi/preflight"
+	"github.com/giantswarm/aws-gs-to-capi/sops"
+)
+
+const (
+	defaultAWSRegion = "eu-west-1"
+
+	// ssmPolicy is the AWS managed policy machines need for Session Manager.
+	ssmPolicy = "AmazonSSMManagedInstanceCore"
+)
+
+// rootFlags are the flags of all commands.
+type rootFlags struct {
+	ClusterID string
+}
+
+func (f *rootFlags) validate() error {
+	if f.ClusterID == "" {
+		return microerror.Maskf(invalidFlagError, "--cluster-id must not be empty")
+	}
+
+	return nil
+}
+
+// New returns the root command of the aws-gs-to-capi command tree.
+func New() *cobra.Command {
+	var f rootFlags
+
+	c := &cobra.Command{
+		Use:   "aws-gs-to-capi",
+		Short: "Migrate Giant Swarm AWS clusters to Cluster API (CAPA).",
+		Long: `Migrate a Giant Swarm AWS cluster from the old management cluster to a
+Cluster API (CAPA) management cluster.
+
+The Giant Swarm CRs are read from the old management cluster given with
+--source-kubeconfig and --source-context, the CAPI resources are created in the
+context given with --context. Both honour $KUBECONFIG.
+
+With --source-bundle the GS CRs, the CA key and the AWS network are read from
+a bundle written by "export" instead, so no access to the old management
+cluster, Vault or AWS is needed.
+
+With --source-api-endpoint the GS CRs are built from the GS REST API, as used
+by gsctl, for when there is no access to the old management cluster. The
+secrets of the cluster have to be given with --source-secrets-dir.`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(c *cobra.Command, args []string) error {
+			// Command groups like "create" only print their usage, so there
+			// is nothing to validate for them.
+			if c.HasSubCommands() {
+				return nil
+			}
+			return f.validate()
+		},
+	}
+
+	c.PersistentFlags().StringVar(&f.ClusterID, "cluster-id", "", "GS cluster ID.")
+
+	c.AddCommand(newCreateCommand(&f))
+	c.AddCommand(newDeleteCommand(&f))
+	c.AddCommand(newUpdateCommand(&f))
+	c.AddCommand(newResumeCommand(&f))
+	c.AddCommand(newPlanCommand(&f))
+	c.AddCommand(newDiffCommand(&f))
+	c.AddCommand(newRenderCommand(&f))
+	c.AddCommand(newApplyCommand(&f))
+	c.AddCommand(newExportCommand(&f))
+
+	return c
+}
+
+// Execute runs the command tree against the process arguments.
+func Execute() error {
+	return New().Execute()
+}
+
+// usage is used by command groups which cannot be executed on their own.
+func usage(c *cobra.Command, args []string) error {
+	_ = c.Usage()
+	return microerror.Maskf(invalidCommandError, "%q requires a subcommand", c.CommandPath())
+}
+
+// source returns the source of the GS cluster given by the flags, which is
+// the GS management cluster by default.
+func source(f *sourceFlags) (giantswarm.Source, error) {
+	switch {
+	case f.SourceBundle != "":
+		identities, err := readIdentities(f.AgeIdentityFile)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		s, err := giantswarm.NewBundleSource(giantswarm.BundleSourceConfig{
+			Path:       f.SourceBundle,
+			Identities: identities,
+		})
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		return s, nil
+	case f.SourceAPIEndpoint != "":
+		s, err := giantswarm.NewRESTSource(giantswarm.RESTSourceConfig{
+			Endpoint:   f.SourceAPIEndpoint,
+			Token:      f.SourceAPIToken,
+			SecretsDir: f.SourceSecretsDir,
+			PodsCIDR:   f.SourcePodsCIDR,
+
+			MasterInstanceType: f.SourceMasterInstanceType,
+		})
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		return s, nil
+	default:
+		s := giantswarm.NewMCSource(giantswarm.ClientConfig{
+			Kubeconfig: f.SourceKubeconfig,
+			Context:    f.SourceContext,
+		})
+
+		return s, nil
+	}
+}
+
+// fetch collects the GS cluster from the source given by the flags.
+func fetch(rf *rootFlags, f *sourceFlags) (*giantswarm.GSClusterCrs, error) {
+	s, err := source(f)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	gsCrs, err := s.Fetch(rf.ClusterID)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	gsCrs.ClusterNetwork, err = clusterNetwork(f, gsCrs.ClusterNetwork)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if wc := workloadClientConfig(f); wc != nil {
+		var ids []string
+		for _, md := range gsCrs.AWSMachineDeployments {
+			ids = append(ids, md.Name)
+		}
+
+		gsCrs.NodePoolNodes, err = giantswarm.FetchWorkloadNodePools(*wc, ids)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	return gsCrs, nil
+}
+
+// workloadClientConfig returns the client config of the running workload
+// cluster, or nil when it is not given.
+func workloadClientConfig(f *sourceFlags) *giantswarm.ClientConfig {
+	if f.WorkloadKubeconfig == "" && f.WorkloadContext == "" {
+		return nil
+	}
+
+	return &giantswarm.ClientConfig{
+		Kubeconfig: f.WorkloadKubeconfig,
+		Context:    f.WorkloadContext,
+	}
+}
+
+// clusterNetwork combines the cluster network of the source with the one of
+// the running workload cluster and the one given by flags, so that every
+// value is cross-checked with all sources which know it.
+func clusterNetwork(f *sourceFlags, n *giantswarm.ClusterNetwork) (*giantswarm.ClusterNetwork, error) {
+	if c := workloadClientConfig(f); c != nil {
+		wc, err := giantswarm.FetchWorkloadClusterNetwork(*c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		n, err = giantswarm.MergeClusterNetwork(n, wc)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	if f.SourceServiceCIDR != "" || f.SourceClusterDomain != "" {
+		var err error
+		n, err = giantswarm.MergeClusterNetwork(n, &giantswarm.ClusterNetwork{
+			ServiceCIDR:   f.SourceServiceCIDR,
+			ClusterDomain: f.SourceClusterDomain,
+		})
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	return n, nil
+}
+
+// transform fetches the GS CRs of the cluster and transforms them into the
+// CAPI CRs.
+func transform(rf *rootFlags, f *transformFlags, k8sVersion string) (*giantswarm.GSClusterCrs, *capi.Crs, error) {
+	labels, err := nodePoolLabels(f.NodePoolLabels)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+	taints, err := nodePoolTaints(f.NodePoolTaints)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	gsCrs, err := fetch(rf, &f.sourceFlags)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	capiCRs, err := capi.TransformGsToCAPICrs(gsCrs, capi.Config{
+		K8sVersion:    k8sVersion,
+		Namespace:     f.TargetNamespace,
+		APIVersion:    f.CAPIAPIVersion,
+		NodePoolMode:  f.NodePoolMode,
+		NodePoolModes: f.NodePoolModes,
+		ClusterClass:  f.ClusterClass,
+		WorkerClass:   f.WorkerClass,
+		SSHKeyName:    f.SSHKeyName,
+		NoSSHKey:      f.NoSSHKey,
+		SSM:           f.SSM,
+
+		ControlPlaneInstanceProfile: f.ControlPlaneInstanceProfile,
+		NodeInstanceProfile:         f.NodeInstanceProfile,
+		NodePoolInstanceProfiles:    f.NodePoolInstanceProfiles,
+
+		LabelPrefixes:      f.PropagateLabels,
+		AnnotationPrefixes: f.PropagateAnnotations,
+		NodePoolLabels:     labels,
+		NodePoolTaints:     taints,
+	})
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	for _, w := range capiCRs.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
+
+	return gsCrs, capiCRs, nil
+}
+
+// runPreflight checks that the AWS resources referenced by the CAPI objects
+// exist, so that CAPA does not fail on them after the objects are created.
+func runPreflight(f *preflightFlags, tf *transformFlags, gsCrs *giantswarm.GSClusterCrs, capiCRs *capi.Crs) error {
+	p, err := preflight.New(preflight.Config{
+		Region: gsCrs.AWSCluster.Spec.Provider.Region,
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if tf.SSHKeyName != "" {
+		err = p.CheckSSHKeyPair(tf.SSHKeyName)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	// A profile used by the control plane and the workers needs the policies
+	// of both.
+	policies := map[string][]string{}
+	cpProfile := capiCRs.ControlPlaneInstanceProfile()
+	policies[cpProfile] = append(policies[cpProfile], f.ControlPlaneInstanceProfilePolicies...)
+	for _, mp := range capiCRs.MachinePools {
+		profile := mp.InstanceProfile()
+		policies[profile] = append(policies[profile], f.NodeInstanceProfilePolicies...)
+	}
+
+	var profiles []string
+	for profile := range policies {
+		profiles = append(profiles, profile)
+		if tf.SSM {
+			policies[profile] = append(policies[profile], ssmPolicy)
+		}
+	}
+	sort.Strings(profiles)
+
+	for _, profile := range profiles {
+		err = p.CheckInstanceProfile(profile, policies[profile])
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		fmt.Printf("Checked IAM instance profile %q\n", profile)
+	}
+
+	return nil
+}
+
+func apiDomain(gsCrs *giantswarm.GSClusterCrs, clusterID string) string {
+	return fmt.Sprintf("%s.k8s.%s", clusterID, gsCrs.AWSCluster.Spec.Cluster.DNS.Domain)
+}
+
+// readIdentities reads the age identities from path. The file is optional,
+// so no identities are returned when it does not exist.
+func readIdentities(path string) ([]age.Identity, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	identities, err := sops.ReadIdentities(path)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return identities, nil
+}
+
+// defaultAgeIdentityFile returns the key file sops uses by default.
+func defaultAgeIdentityFile() string {
+	p, ok := os.LookupEnv("SOPS_AGE_KEY_FILE")
+	if ok {
+		return p
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "sops", "age", "keys.txt")
+}
+
+func contains(l []string, s string) bool {
+	for _, e := range l {
+		if e == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func values(m map[string]string) []string {
+	var l []string
+	for _, v := range m {
+		l = append(l, v)
+	}
+
+	return l
+}
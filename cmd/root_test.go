@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// Test_New_Flags checks that commands only accept the flags they read.
+func Test_New_Flags(t *testing.T) {
+	testCases := []struct {
+		name     string
+		command  string
+		flags    []string
+		rejected []string
+	}{
+		{
+			name:     "case 0: root",
+			command:  "",
+			flags:    []string{"cluster-id"},
+			rejected: []string{"context", "source-bundle", "capi-api-version", "skip-preflight"},
+		},
+		{
+			name:    "case 1: create all",
+			command: "create all",
+			flags:   []string{"cluster-id", "context", "state-file", "aws-region", "k8s-version", "source-bundle", "capi-api-version", "node-pool-label", "skip-preflight", "node-instance-profile-policies"},
+		},
+		{
+			name:     "case 2: update dns",
+			command:  "update dns",
+			flags:    []string{"context", "state-file", "aws-region", "source-bundle", "capi-api-version"},
+			rejected: []string{"skip-preflight", "k8s-version"},
+		},
+		{
+			name:     "case 3: delete cp",
+			command:  "delete cp",
+			flags:    []string{"context", "state-file", "source-bundle", "capi-api-version"},
+			rejected: []string{"aws-region", "skip-preflight"},
+		},
+		{
+			name:    "case 4: resume",
+			command: "resume",
+			flags:   []string{"context", "state-file", "aws-region", "k8s-version", "source-bundle", "skip-preflight"},
+		},
+		{
+			name:     "case 5: render",
+			command:  "render",
+			flags:    []string{"age-recipient", "age-identity-file", "source-bundle", "capi-api-version", "layout"},
+			rejected: []string{"context", "state-file", "skip-preflight"},
+		},
+		{
+			name:     "case 6: diff",
+			command:  "diff",
+			flags:    []string{"context", "source-bundle", "capi-api-version"},
+			rejected: []string{"state-file", "skip-preflight"},
+		},
+		{
+			name:     "case 7: plan",
+			command:  "plan",
+			flags:    []string{"aws-region", "state-file", "source-bundle", "capi-api-version"},
+			rejected: []string{"context", "skip-preflight"},
+		},
+		{
+			name:     "case 8: apply",
+			command:  "apply",
+			flags:    []string{"cluster-id", "context", "age-identity-file", "from-dir"},
+			rejected: []string{"source-bundle", "capi-api-version", "state-file"},
+		},
+		{
+			name:     "case 9: export",
+			command:  "export",
+			flags:    []string{"output", "age-recipient", "source-bundle", "source-context", "workload-kubeconfig"},
+			rejected: []string{"context", "capi-api-version", "node-pool-label"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, _, err := New().Find(strings.Fields(tc.command))
+			if err != nil {
+				t.Fatalf("Find() error = %v", err)
+			}
+
+			for _, f := range tc.flags {
+				if !hasFlag(c, f) {
+					t.Errorf("%q does not accept --%s", tc.command, f)
+				}
+			}
+			for _, f := range tc.rejected {
+				if hasFlag(c, f) {
+					t.Errorf("%q accepts --%s", tc.command, f)
+				}
+			}
+		})
+	}
+}
+
+func hasFlag(c *cobra.Command, name string) bool {
+	return c.Flags().Lookup(name) != nil || c.PersistentFlags().Lookup(name) != nil || c.InheritedFlags().Lookup(name) != nil
+}
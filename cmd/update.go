@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/aws-gs-to-capi/state"
+)
+
+type updateFlags struct {
+	migrationFlags
+
+	AWSRegion string
+}
+
+func newUpdateCommand(rf *rootFlags) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "update",
+		Short: "Update external resources of the migrated cluster.",
+		Args:  cobra.NoArgs,
+		RunE:  usage,
+	}
+
+	c.AddCommand(newUpdateCPCommand(rf))
+	c.AddCommand(newUpdateDNSCommand(rf))
+
+	return c
+}
+
+func newUpdateCPCommand(rf *rootFlags) *cobra.Command {
+	var f updateFlags
+
+	c := &cobra.Command{
+		Use:   "cp",
+		Short: "Grow the new control plane to the replicas of the GS control plane.",
+		Long: `Grow the new control plane to the replicas of the GS control plane.
+
+The control plane is created with a single replica. Every new machine joins
+the etcd cluster, so the replicas are increased one at a time, each time
+after all machines are ready.`,
+		Args: cobra.NoArgs,
+		PreRunE: func(c *cobra.Command, args []string) error {
+			return f.validate()
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			m, err := newMigration(rf, &f.migrationFlags, "", "")
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			err = m.run(nil, state.PhaseControlPlaneScale)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			return nil
+		},
+	}
+
+	f.migrationFlags.add(c)
+
+	return c
+}
+
+func newUpdateDNSCommand(rf *rootFlags) *cobra.Command {
+	var f updateFlags
+
+	c := &cobra.Command{
+		Use:   "dns",
+		Short: "Point the API DNS record to the ELB of the new control plane.",
+		Long: `Point the API DNS record to the ELB of the new control plane.
+
+The original target of the record is kept in the state file.`,
+		Args: cobra.NoArgs,
+		PreRunE: func(c *cobra.Command, args []string) error {
+			return f.validate()
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			m, err := newMigration(rf, &f.migrationFlags, f.AWSRegion, "")
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			err = m.run(nil, state.PhaseDNS)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			return nil
+		},
+	}
+
+	f.migrationFlags.add(c)
+	c.Flags().StringVar(&f.AWSRegion, "aws-region", "", "AWS Region. Defaults to the region the migration was started with, or the region of the GS cluster.")
+
+	return c
+}
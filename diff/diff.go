@@ -0,0 +1,287 @@
+// Package diff compares the desired CAPI objects with the objects live on the
+// CAPI management cluster.
+//
+// Only fields set in the desired objects are compared. Fields which are only
+// present in the live objects are populated by the API server or by
+// controllers, e.g. defaults, status and metadata like the resource version,
+// and are ignored.
+package diff
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	ChangeMissing = "missing"
+	ChangeChanged = "changed"
+
+	sensitiveValue = "<sensitive>"
+)
+
+// ignoredMetadata are metadata fields populated by the API server.
+var ignoredMetadata = []string{
+	"creationTimestamp",
+	"deletionGracePeriodSeconds",
+	"deletionTimestamp",
+	"generation",
+	"managedFields",
+	"resourceVersion",
+	"selfLink",
+	"uid",
+}
+
+type Change struct {
+	Type    string
+	Path    string
+	Desired interface{}
+	Live    interface{}
+}
+
+type ObjectDiff struct {
+	Kind      string
+	Namespace string
+	Name      string
+
+	NotFound bool
+	Changes  []Change
+}
+
+// HasDrift returns true if the live object is missing or differs from the
+// desired object.
+func (d ObjectDiff) HasDrift() bool {
+	return d.NotFound || len(d.Changes) > 0
+}
+
+// Objects fetches every desired object from the cluster and compares it with
+// the live object.
+func Objects(ctx context.Context, c client.Client, objs []runtime.Object) ([]ObjectDiff, error) {
+	var diffs []ObjectDiff
+	for _, o := range objs {
+		d, err := object(ctx, c, o)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		diffs = append(diffs, d)
+	}
+
+	return diffs, nil
+}
+
+// HasDrift returns true if any of the objects has drifted.
+func HasDrift(diffs []ObjectDiff) bool {
+	for _, d := range diffs {
+		if d.HasDrift() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Print writes the diffs in a human readable form. Values of Secret data are
+// never printed.
+func Print(w io.Writer, diffs []ObjectDiff) error {
+	var b strings.Builder
+
+	for _, d := range diffs {
+		id := fmt.Sprintf("%s %s/%s", d.Kind, d.Namespace, d.Name)
+		switch {
+		case d.NotFound:
+			fmt.Fprintf(&b, "! %s: not found\n", id)
+		case len(d.Changes) == 0:
+			fmt.Fprintf(&b, "= %s: in sync\n", id)
+		default:
+			fmt.Fprintf(&b, "~ %s: %d changes\n", id, len(d.Changes))
+			for _, c := range d.Changes {
+				desired, live := format(c.Desired), format(c.Live)
+				if isSensitive(d.Kind, c.Path) {
+					desired, live = sensitiveValue, sensitiveValue
+				}
+
+				switch c.Type {
+				case ChangeMissing:
+					fmt.Fprintf(&b, "    + %s: %s\n", c.Path, desired)
+				default:
+					fmt.Fprintf(&b, "    ~ %s: %s -> %s\n", c.Path, live, desired)
+				}
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func object(ctx context.Context, c client.Client, o runtime.Object) (ObjectDiff, error) {
+	m, err := meta.Accessor(o)
+	if err != nil {
+		return ObjectDiff{}, microerror.Mask(err)
+	}
+	gvk := o.GetObjectKind().GroupVersionKind()
+
+	d := ObjectDiff{
+		Kind:      gvk.Kind,
+		Namespace: m.GetNamespace(),
+		Name:      m.GetName(),
+	}
+
+	desired, err := runtime.DefaultUnstructuredConverter.ToUnstructured(o)
+	if err != nil {
+		return ObjectDiff{}, microerror.Mask(err)
+	}
+	clean(desired)
+
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(gvk)
+	err = c.Get(ctx, client.ObjectKey{Namespace: d.Namespace, Name: d.Name}, live)
+	if apierrors.IsNotFound(err) {
+		d.NotFound = true
+		return d, nil
+	} else if err != nil {
+		return ObjectDiff{}, microerror.Mask(err)
+	}
+
+	d.Changes = Compare(desired, live.Object)
+
+	return d, nil
+}
+
+// Compare returns the changes needed to turn the live fields into the desired
+// fields. Fields missing in the desired object are ignored.
+func Compare(desired map[string]interface{}, live map[string]interface{}) []Change {
+	var changes []Change
+	compareMap("", desired, live, &changes)
+
+	return changes
+}
+
+func compareMap(path string, desired map[string]interface{}, live map[string]interface{}, changes *[]Change) {
+	keys := make([]string, 0, len(desired))
+	for k := range desired {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		compareValue(join(path, k), desired[k], live[k], live != nil && hasKey(live, k), changes)
+	}
+}
+
+func compareValue(path string, desired interface{}, live interface{}, found bool, changes *[]Change) {
+	// Empty desired values are dropped or defaulted by the API server.
+	if isEmpty(desired) {
+		return
+	}
+	if !found {
+		*changes = append(*changes, Change{Type: ChangeMissing, Path: path, Desired: desired})
+		return
+	}
+
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			*changes = append(*changes, Change{Type: ChangeChanged, Path: path, Desired: desired, Live: live})
+			return
+		}
+		compareMap(path, d, l, changes)
+
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if !ok || len(l) != len(d) {
+			*changes = append(*changes, Change{Type: ChangeChanged, Path: path, Desired: desired, Live: live})
+			return
+		}
+		for i := range d {
+			compareValue(fmt.Sprintf("%s[%d]", path, i), d[i], l[i], true, changes)
+		}
+
+	default:
+		if !reflect.DeepEqual(normalize(desired), normalize(live)) {
+			*changes = append(*changes, Change{Type: ChangeChanged, Path: path, Desired: desired, Live: live})
+		}
+	}
+}
+
+// clean removes the fields of the desired object which are populated by the
+// API server.
+func clean(o map[string]interface{}) {
+	delete(o, "status")
+
+	m, ok := o["metadata"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for _, f := range ignoredMetadata {
+		delete(m, f)
+	}
+}
+
+func hasKey(m map[string]interface{}, k string) bool {
+	_, ok := m[k]
+	return ok
+}
+
+func isEmpty(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+
+	return false
+}
+
+// normalize converts numbers to float64, because the desired and the live
+// objects are decoded differently.
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	}
+
+	return v
+}
+
+func isSensitive(kind string, path string) bool {
+	return kind == "Secret" && (strings.HasPrefix(path, "data") || strings.HasPrefix(path, "stringData"))
+}
+
+func format(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+
+	return fmt.Sprintf("%v", v)
+}
+
+func join(path string, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
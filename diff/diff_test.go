@@ -0,0 +1,206 @@
+package diff
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_Objects(t *testing.T) {
+	testCases := []struct {
+		name          string
+		desired       runtime.Object
+		live          []runtime.Object
+		expectedDiffs []ObjectDiff
+		expectedPrint string
+	}{
+		{
+			name:    "case 0: unchanged",
+			desired: newConfigMap(nil, map[string]string{"a": "1"}),
+			live:    []runtime.Object{newConfigMap(nil, map[string]string{"a": "1"})},
+			expectedDiffs: []ObjectDiff{
+				{Kind: "ConfigMap", Namespace: "org-acme", Name: "abc12"},
+			},
+			expectedPrint: "= ConfigMap org-acme/abc12: in sync\n",
+		},
+		{
+			name:    "case 1: changed field",
+			desired: newConfigMap(nil, map[string]string{"a": "1", "b": "2"}),
+			live:    []runtime.Object{newConfigMap(nil, map[string]string{"a": "1", "b": "3"})},
+			expectedDiffs: []ObjectDiff{
+				{
+					Kind:      "ConfigMap",
+					Namespace: "org-acme",
+					Name:      "abc12",
+					Changes: []Change{
+						{Type: ChangeChanged, Path: "data.b", Desired: "2", Live: "3"},
+					},
+				},
+			},
+			expectedPrint: "~ ConfigMap org-acme/abc12: 1 changes\n    ~ data.b: \"3\" -> \"2\"\n",
+		},
+		{
+			name:    "case 2: missing field",
+			desired: newConfigMap(map[string]string{"team": "data"}, map[string]string{"a": "1"}),
+			live:    []runtime.Object{newConfigMap(nil, map[string]string{"a": "1"})},
+			expectedDiffs: []ObjectDiff{
+				{
+					Kind:      "ConfigMap",
+					Namespace: "org-acme",
+					Name:      "abc12",
+					Changes: []Change{
+						{Type: ChangeMissing, Path: "metadata.labels", Desired: map[string]interface{}{"team": "data"}},
+					},
+				},
+			},
+			expectedPrint: "~ ConfigMap org-acme/abc12: 1 changes\n    + metadata.labels: map[team:data]\n",
+		},
+		{
+			name:    "case 3: missing object",
+			desired: newConfigMap(nil, map[string]string{"a": "1"}),
+			expectedDiffs: []ObjectDiff{
+				{Kind: "ConfigMap", Namespace: "org-acme", Name: "abc12", NotFound: true},
+			},
+			expectedPrint: "! ConfigMap org-acme/abc12: not found\n",
+		},
+		{
+			name:    "case 4: server-only fields are ignored",
+			desired: newConfigMap(nil, map[string]string{"a": "1"}),
+			live: []runtime.Object{
+				func() runtime.Object {
+					cm := newConfigMap(map[string]string{"controller": "x"}, map[string]string{"a": "1"})
+					cm.Annotations = map[string]string{"kubectl.kubernetes.io/last-applied-configuration": "{}"}
+					cm.UID = "1234"
+					cm.Generation = 3
+					return cm
+				}(),
+			},
+			expectedDiffs: []ObjectDiff{
+				{Kind: "ConfigMap", Namespace: "org-acme", Name: "abc12"},
+			},
+			expectedPrint: "= ConfigMap org-acme/abc12: in sync\n",
+		},
+		{
+			name:    "case 5: Secret data is masked",
+			desired: newSecret(map[string][]byte{"key": []byte("new"), "ca": []byte("ca")}),
+			live:    []runtime.Object{newSecret(map[string][]byte{"key": []byte("old"), "ca": []byte("ca")})},
+			expectedDiffs: []ObjectDiff{
+				{
+					Kind:      "Secret",
+					Namespace: "org-acme",
+					Name:      "abc12",
+					Changes: []Change{
+						{Type: ChangeChanged, Path: "data.key", Desired: "bmV3", Live: "b2xk"},
+					},
+				},
+			},
+			expectedPrint: "~ Secret org-acme/abc12: 1 changes\n    ~ data.key: <sensitive> -> <sensitive>\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := fake.NewFakeClientWithScheme(scheme.Scheme, tc.live...)
+
+			diffs, err := Objects(context.Background(), c, []runtime.Object{tc.desired})
+			if err != nil {
+				t.Fatalf("Objects() error = %v", err)
+			}
+			if !reflect.DeepEqual(diffs, tc.expectedDiffs) {
+				t.Fatalf("Objects() = %#v, want %#v", diffs, tc.expectedDiffs)
+			}
+
+			var b bytes.Buffer
+			err = Print(&b, diffs)
+			if err != nil {
+				t.Fatalf("Print() error = %v", err)
+			}
+			if b.String() != tc.expectedPrint {
+				t.Fatalf("Print() =\n%s\nwant\n%s", b.String(), tc.expectedPrint)
+			}
+		})
+	}
+}
+
+func Test_Compare(t *testing.T) {
+	testCases := []struct {
+		name     string
+		desired  map[string]interface{}
+		live     map[string]interface{}
+		expected []Change
+	}{
+		{
+			name:    "case 0: numbers decoded differently are equal",
+			desired: map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(3)}},
+			live:    map[string]interface{}{"spec": map[string]interface{}{"replicas": float64(3)}},
+		},
+		{
+			name:    "case 1: empty desired values are ignored",
+			desired: map[string]interface{}{"spec": map[string]interface{}{"taints": []interface{}{}, "labels": nil}},
+			live:    map[string]interface{}{"spec": map[string]interface{}{}},
+		},
+		{
+			name:    "case 2: lists of different length",
+			desired: map[string]interface{}{"subnets": []interface{}{"a", "b"}},
+			live:    map[string]interface{}{"subnets": []interface{}{"a"}},
+			expected: []Change{
+				{Type: ChangeChanged, Path: "subnets", Desired: []interface{}{"a", "b"}, Live: []interface{}{"a"}},
+			},
+		},
+		{
+			name:    "case 3: changed list item",
+			desired: map[string]interface{}{"subnets": []interface{}{map[string]interface{}{"id": "a"}}},
+			live:    map[string]interface{}{"subnets": []interface{}{map[string]interface{}{"id": "b", "cidr": "10.0.0.0/24"}}},
+			expected: []Change{
+				{Type: ChangeChanged, Path: "subnets[0].id", Desired: "a", Live: "b"},
+			},
+		},
+		{
+			name:    "case 4: map replaced by a scalar",
+			desired: map[string]interface{}{"spec": map[string]interface{}{"a": "b"}},
+			live:    map[string]interface{}{"spec": "x"},
+			expected: []Change{
+				{Type: ChangeChanged, Path: "spec", Desired: map[string]interface{}{"a": "b"}, Live: "x"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			changes := Compare(tc.desired, tc.live)
+			if !reflect.DeepEqual(changes, tc.expected) {
+				t.Fatalf("Compare() = %#v, want %#v", changes, tc.expected)
+			}
+		})
+	}
+}
+
+func newConfigMap(labels map[string]string, data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "abc12",
+			Namespace: "org-acme",
+			Labels:    labels,
+		},
+		Data: data,
+	}
+}
+
+func newSecret(data map[string][]byte) *corev1.Secret {
+	return &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "abc12",
+			Namespace: "org-acme",
+		},
+		Data: data,
+	}
+}
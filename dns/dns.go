@@ -10,14 +10,14 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/route53"
 	"github.com/giantswarm/microerror"
-	awsv1alpha3 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	ctrl "sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/giantswarm/aws-gs-to-capi/ctrlclient"
 )
 
-func UpdateAPIDNSToNewELB(clusterID string, dnsDomain string, region string, k8sContext string) error {
-	lbDNSName, lbName, err := waitForAPIELBName(clusterID, k8sContext)
+func UpdateAPIDNSToNewELB(clusterID string, namespace string, dnsDomain string, region string, k8sContext string, capiAPIVersion string) error {
+	lbDNSName, lbName, err := waitForAPIELBName(clusterID, namespace, k8sContext, capiAPIVersion)
 	if err != nil {
 		return microerror.Mask(err)
 	}
@@ -30,31 +30,54 @@ func UpdateAPIDNSToNewELB(clusterID string, dnsDomain string, region string, k8s
 	return nil
 }
 
-func waitForAPIELBName(clusterID string, k8sContext string) (string, string, error) {
+// waitForAPIELBName waits for the API ELB of the AWSCluster of the given CAPI
+// API version. The AWSCluster is read unstructured, since the ELB moved from
+// status.network to status.networkStatus with v1alpha4.
+func waitForAPIELBName(clusterID string, namespace string, k8sContext string, capiAPIVersion string) (string, string, error) {
 	ctrlClient, err := ctrlclient.GetCtrlClient(k8sContext)
 	if err != nil {
 		return "", "", microerror.Mask(err)
 	}
-	var awsCluster awsv1alpha3.AWSCluster
+	var awsCluster unstructured.Unstructured
+	awsCluster.SetAPIVersion("infrastructure.cluster.x-k8s.io/" + capiAPIVersion)
+	awsCluster.SetKind("AWSCluster")
+
+	networkStatus := "networkStatus"
+	if capiAPIVersion == "v1alpha3" {
+		networkStatus = "network"
+	}
 
 	waitCounter := 0
 
 	ctx := context.Background()
 	for {
-		err := ctrlClient.Get(ctx,
-			ctrl.ObjectKey{
-				Name:      clusterID,
-				Namespace: "default",
-			},
-			&awsCluster,
-		)
+		// The AWSCluster of a Cluster created from a ClusterClass has a
+		// generated name, so it is looked up via the infrastructure reference.
+		awsClusterName, err := infrastructureRefName(ctx, ctrlClient, clusterID, namespace, capiAPIVersion)
 		if err != nil {
 			return "", "", microerror.Mask(err)
 		}
 
-		if awsCluster.Status.Network.APIServerELB.DNSName != "" {
-			fmt.Printf("Fetched new API ELB DNS '%s'\n", awsCluster.Status.Network.APIServerELB.DNSName)
-			return awsCluster.Status.Network.APIServerELB.DNSName, awsCluster.Status.Network.APIServerELB.Name, nil
+		var lbDNSName, lbName string
+		if awsClusterName != "" {
+			err = ctrlClient.Get(ctx,
+				ctrl.ObjectKey{
+					Name:      awsClusterName,
+					Namespace: namespace,
+				},
+				&awsCluster,
+			)
+			if err != nil {
+				return "", "", microerror.Mask(err)
+			}
+
+			lbDNSName, _, _ = unstructured.NestedString(awsCluster.Object, "status", networkStatus, "apiServerElb", "dnsName")
+			lbName, _, _ = unstructured.NestedString(awsCluster.Object, "status", networkStatus, "apiServerElb", "name")
+		}
+
+		if lbDNSName != "" {
+			fmt.Printf("Fetched new API ELB DNS '%s'\n", lbDNSName)
+			return lbDNSName, lbName, nil
 		} else {
 			fmt.Printf("API  DNS name is not ready yet, sleeping for 10s ...\n")
 			time.Sleep(time.Second * 10)
@@ -67,6 +90,32 @@ func waitForAPIELBName(clusterID string, k8sContext string) (string, string, err
 	}
 }
 
+// infrastructureRefName returns the name of the infrastructure cluster of the
+// given Cluster. It is empty as long as the reference is not set.
+func infrastructureRefName(ctx context.Context, ctrlClient ctrl.Client, clusterID string, namespace string, capiAPIVersion string) (string, error) {
+	var cluster unstructured.Unstructured
+	cluster.SetAPIVersion("cluster.x-k8s.io/" + capiAPIVersion)
+	cluster.SetKind("Cluster")
+
+	err := ctrlClient.Get(ctx,
+		ctrl.ObjectKey{
+			Name:      clusterID,
+			Namespace: namespace,
+		},
+		&cluster,
+	)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	name, _, err := unstructured.NestedString(cluster.Object, "spec", "infrastructureRef", "name")
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return name, nil
+}
+
 func updateAPIDNS(dnsDomain string, lbName string, lbDNS string, lbRegion string) error {
 	awsSession, err := getAWSSession(lbRegion)
 	if err != nil {
@@ -142,8 +191,57 @@ func updateAPIDNS(dnsDomain string, lbName string, lbDNS string, lbRegion string
 	return nil
 }
 
-func DeleteDNSRecords(clusterID string, dnsDomain string, lbRegion string, k8sContext string) error {
-	lbDNS, lbName, err := waitForAPIELBName(clusterID, k8sContext)
+// GetAPIRecordTarget returns the current target of the API DNS record, i.e.
+// the alias target or the first value of the record.
+func GetAPIRecordTarget(dnsDomain string, region string) (string, error) {
+	awsSession, err := getAWSSession(region)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	r53Client := route53.New(awsSession)
+
+	o, err := r53Client.ListHostedZonesByName(&route53.ListHostedZonesByNameInput{
+		DNSName: aws.String(fmt.Sprintf("%s.", dnsDomain)),
+	})
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	recordName := fmt.Sprintf("api.%s.", dnsDomain)
+	for _, hz := range o.HostedZones {
+		if *hz.Name != fmt.Sprintf("%s.", dnsDomain) {
+			continue
+		}
+
+		o2, err := r53Client.ListResourceRecordSets(&route53.ListResourceRecordSetsInput{
+			HostedZoneId:    hz.Id,
+			StartRecordName: aws.String(recordName),
+			StartRecordType: aws.String("A"),
+			MaxItems:        aws.String("1"),
+		})
+		if err != nil {
+			return "", microerror.Mask(err)
+		}
+
+		for _, rs := range o2.ResourceRecordSets {
+			if *rs.Name != recordName || *rs.Type != "A" {
+				continue
+			}
+			if rs.AliasTarget != nil {
+				return *rs.AliasTarget.DNSName, nil
+			}
+			if len(rs.ResourceRecords) > 0 {
+				return *rs.ResourceRecords[0].Value, nil
+			}
+		}
+	}
+
+	return "", microerror.Maskf(nil, "API DNS record 'api.%s' not found", dnsDomain)
+}
+
+func DeleteDNSRecords(clusterID string, namespace string, dnsDomain string, lbRegion string, k8sContext string, capiAPIVersion string) error {
+	lbDNS, lbName, err := waitForAPIELBName(clusterID, namespace, k8sContext, capiAPIVersion)
 	if err != nil {
 		return microerror.Mask(err)
 	}
@@ -0,0 +1,203 @@
+package giantswarm
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/giantswarm/microerror"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	infrastructureGroup = "infrastructure.giantswarm.io"
+
+	APIVersionV1alpha2 = "v1alpha2"
+	APIVersionV1alpha3 = "v1alpha3"
+
+	kindAWSCluster           = "AWSCluster"
+	kindAWSControlPlane      = "AWSControlPlane"
+	kindAWSMachineDeployment = "AWSMachineDeployment"
+	kindG8sControlPlane      = "G8sControlPlane"
+)
+
+// apiVersions are the supported versions of the GS infrastructure CRs, the
+// newest first.
+var apiVersions = []string{
+	APIVersionV1alpha3,
+	APIVersionV1alpha2,
+}
+
+// resources maps the kinds of the GS infrastructure CRs to their resources.
+var resources = map[string]string{
+	kindAWSCluster:           "awsclusters",
+	kindAWSControlPlane:      "awscontrolplanes",
+	kindAWSMachineDeployment: "awsmachinedeployments",
+	kindG8sControlPlane:      "g8scontrolplanes",
+}
+
+// DetectAPIVersion returns the newest supported version of the GS
+// infrastructure CRs which is served with all kinds on the management
+// cluster.
+func DetectAPIVersion(d discovery.DiscoveryInterface) (string, error) {
+	for _, v := range apiVersions {
+		l, err := d.ServerResourcesForGroupVersion(schema.GroupVersion{Group: infrastructureGroup, Version: v}.String())
+		if apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return "", microerror.Mask(err)
+		}
+
+		served := map[string]bool{}
+		for _, r := range l.APIResources {
+			served[r.Name] = true
+		}
+
+		servesAll := true
+		for _, r := range resources {
+			servesAll = servesAll && served[r]
+		}
+		if servesAll {
+			return v, nil
+		}
+	}
+
+	return "", microerror.Maskf(notFoundError, "none of the versions %v of %s is served on the management cluster", apiVersions, infrastructureGroup)
+}
+
+// crReader reads the GS infrastructure CRs of one version with the dynamic
+// client. The CRs of all versions are decoded into the v1alpha2 types, which
+// are the internal model of the transformation. Decoding fails when the spec
+// of a CR has fields the v1alpha2 types do not have.
+type crReader struct {
+	client     dynamic.Interface
+	apiVersion string
+}
+
+// list returns the CRs of kind in namespace which belong to the cluster. All
+// namespaces are searched if namespace is empty.
+func (r *crReader) list(kind string, namespace string, clusterID string) ([]unstructured.Unstructured, error) {
+	gvr := schema.GroupVersionResource{
+		Group:    infrastructureGroup,
+		Version:  r.apiVersion,
+		Resource: resources[kind],
+	}
+
+	l, err := r.client.Resource(gvr).Namespace(namespace).List(metav1.ListOptions{
+		LabelSelector: clusterSelector(clusterID),
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return l.Items, nil
+}
+
+// listOne is like list but expects exactly one CR and decodes it into obj.
+func (r *crReader) listOne(kind string, namespace string, clusterID string, obj interface{}) error {
+	l, err := r.list(kind, namespace, clusterID)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	if len(l) != 1 {
+		return microerror.Maskf(executionFailedError, "expected 1 %s but got %d for cluster id %s", kind, len(l), clusterID)
+	}
+
+	return microerror.Mask(fromUnstructured(l[0], obj))
+}
+
+// fromUnstructured decodes u into obj. Fields of the spec of u which obj does
+// not have are an error, so that a field renamed or moved in a newer version
+// of the CRs is not dropped silently.
+func fromUnstructured(u unstructured.Unstructured, obj interface{}) error {
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, obj)
+	if err != nil {
+		return microerror.Maskf(executionFailedError, "failed to decode %s %s/%s: %s", u.GetKind(), u.GetNamespace(), u.GetName(), err)
+	}
+
+	decoded, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	field := unknownField(u.Object["spec"], decoded["spec"], "spec")
+	if field != "" {
+		return microerror.Maskf(executionFailedError, "failed to decode %s %s/%s: field %s of %s is not supported", u.GetKind(), u.GetNamespace(), u.GetName(), field, u.GetAPIVersion())
+	}
+
+	return nil
+}
+
+// unknownField returns the path of the first field of in which is missing in
+// decoded, or an empty string when there is none. Fields with zero values are
+// ignored, since they are left out when decoded is encoded again.
+func unknownField(in interface{}, decoded interface{}, path string) string {
+	switch in := in.(type) {
+	case map[string]interface{}:
+		d, _ := decoded.(map[string]interface{})
+
+		var keys []string
+		for k := range in {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			p := fmt.Sprintf("%s.%s", path, k)
+			dv, ok := d[k]
+			if !ok {
+				if isZero(in[k]) {
+					continue
+				}
+				return p
+			}
+			if f := unknownField(in[k], dv, p); f != "" {
+				return f
+			}
+		}
+	case []interface{}:
+		d, _ := decoded.([]interface{})
+		for i, v := range in {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			if i >= len(d) {
+				return p
+			}
+			if f := unknownField(v, d[i], p); f != "" {
+				return f
+			}
+		}
+	}
+
+	return ""
+}
+
+func isZero(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case bool:
+		return !v
+	case int64:
+		return v == 0
+	case float64:
+		return v == 0
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		for _, e := range v {
+			if !isZero(e) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
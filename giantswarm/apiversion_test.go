@@ -0,0 +1,188 @@
+package giantswarm
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	awsv1alpha2 "github.com/giantswarm/apiextensions/pkg/apis/infrastructure/v1alpha2"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+func readUnstructured(t *testing.T, file string) unstructured.Unstructured {
+	t.Helper()
+
+	b, err := ioutil.ReadFile(filepath.Join("testdata", file))
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile() error = %v", err)
+	}
+
+	var u unstructured.Unstructured
+	err = yaml.Unmarshal(b, &u.Object)
+	if err != nil {
+		t.Fatalf("yaml.Unmarshal() error = %v", err)
+	}
+
+	return u
+}
+
+type field struct {
+	name      string
+	got, want interface{}
+}
+
+func checkFields(t *testing.T, fields []field) {
+	t.Helper()
+
+	for _, f := range fields {
+		if !reflect.DeepEqual(f.got, f.want) {
+			t.Errorf("%s = %#v, want %#v", f.name, f.got, f.want)
+		}
+	}
+}
+
+// Test_fromUnstructured_V1alpha3 decodes v1alpha3 CRs as served by a GS
+// management cluster into the v1alpha2 types and checks all fields the
+// transformation reads.
+func Test_fromUnstructured_V1alpha3(t *testing.T) {
+	t.Run("AWSCluster", func(t *testing.T) {
+		c := &awsv1alpha2.AWSCluster{}
+		err := fromUnstructured(readUnstructured(t, "v1alpha3/awscluster.yaml"), c)
+		if err != nil {
+			t.Fatalf("fromUnstructured() error = %v", err)
+		}
+
+		checkFields(t, []field{
+			{name: "name", got: c.Name, want: "abc12"},
+			{name: "namespace", got: c.Namespace, want: "org-acme"},
+			{name: "organization label", got: c.Labels["giantswarm.io/organization"], want: "acme"},
+			{name: "release label", got: c.Labels["release.giantswarm.io/version"], want: "16.0.1"},
+			{name: "docs annotation", got: c.Annotations["giantswarm.io/docs"], want: "https://docs.giantswarm.io/ui-api/management-api/crd/awsclusters.infrastructure.giantswarm.io/"},
+			{name: "description", got: c.Spec.Cluster.Description, want: "prod cluster"},
+			{name: "DNS domain", got: c.Spec.Cluster.DNS.Domain, want: "gauss.eu-west-1.aws.gigantic.io"},
+			{name: "region", got: c.Spec.Provider.Region, want: "eu-west-1"},
+			{name: "pods CIDR", got: c.Spec.Provider.Pods.CIDRBlock, want: "10.2.0.0/16"},
+			{name: "VPC ID", got: c.Status.Provider.Network.VPCID, want: "vpc-0123456789abcdef0"},
+			{name: "VPC CIDR", got: c.Status.Provider.Network.CIDR, want: "10.1.0.0/24"},
+		})
+	})
+
+	t.Run("AWSControlPlane", func(t *testing.T) {
+		cp := &awsv1alpha2.AWSControlPlane{}
+		err := fromUnstructured(readUnstructured(t, "v1alpha3/awscontrolplane.yaml"), cp)
+		if err != nil {
+			t.Fatalf("fromUnstructured() error = %v", err)
+		}
+
+		checkFields(t, []field{
+			{name: "name", got: cp.Name, want: "a1b2c"},
+			{name: "availability zones", got: cp.Spec.AvailabilityZones, want: []string{"eu-west-1a", "eu-west-1b", "eu-west-1c"}},
+			{name: "instance type", got: cp.Spec.InstanceType, want: "m5.2xlarge"},
+		})
+	})
+
+	t.Run("G8sControlPlane", func(t *testing.T) {
+		cp := &awsv1alpha2.G8sControlPlane{}
+		err := fromUnstructured(readUnstructured(t, "v1alpha3/g8scontrolplane.yaml"), cp)
+		if err != nil {
+			t.Fatalf("fromUnstructured() error = %v", err)
+		}
+
+		checkFields(t, []field{
+			{name: "name", got: cp.Name, want: "a1b2c"},
+			{name: "replicas", got: cp.Spec.Replicas, want: 3},
+		})
+	})
+
+	t.Run("AWSMachineDeployment", func(t *testing.T) {
+		md := &awsv1alpha2.AWSMachineDeployment{}
+		err := fromUnstructured(readUnstructured(t, "v1alpha3/awsmachinedeployment.yaml"), md)
+		if err != nil {
+			t.Fatalf("fromUnstructured() error = %v", err)
+		}
+
+		checkFields(t, []field{
+			{name: "name", got: md.Name, want: "np001"},
+			{name: "machine deployment label", got: md.Labels["giantswarm.io/machine-deployment"], want: "np001"},
+			{name: "description", got: md.Spec.NodePool.Description, want: "workers"},
+			{name: "docker volume", got: md.Spec.NodePool.Machine.DockerVolumeSizeGB, want: 100},
+			{name: "kubelet volume", got: md.Spec.NodePool.Machine.KubeletVolumeSizeGB, want: 50},
+			{name: "scaling min", got: md.Spec.NodePool.Scaling.Min, want: 2},
+			{name: "scaling max", got: md.Spec.NodePool.Scaling.Max, want: 5},
+			{name: "availability zones", got: md.Spec.Provider.AvailabilityZones, want: []string{"eu-west-1a", "eu-west-1b"}},
+			{name: "on-demand base capacity", got: md.Spec.Provider.InstanceDistribution.OnDemandBaseCapacity, want: 1},
+			{name: "on-demand percentage", got: *md.Spec.Provider.InstanceDistribution.OnDemandPercentageAboveBaseCapacity, want: 50},
+			{name: "instance type", got: md.Spec.Provider.Worker.InstanceType, want: "m5.large"},
+			{name: "alike instance types", got: md.Spec.Provider.Worker.UseAlikeInstanceTypes, want: true},
+			{name: "status instance types", got: md.Status.Provider.Worker.InstanceTypes, want: []string{"m5.large", "m4.large"}},
+		})
+	})
+}
+
+func Test_fromUnstructured_UnknownField(t *testing.T) {
+	testCases := []struct {
+		name   string
+		file   string
+		obj    interface{}
+		modify func(u unstructured.Unstructured) error
+	}{
+		{
+			name: "case 0: renamed field",
+			file: "v1alpha3/awsmachinedeployment.yaml",
+			obj:  &awsv1alpha2.AWSMachineDeployment{},
+			modify: func(u unstructured.Unstructured) error {
+				unstructured.RemoveNestedField(u.Object, "spec", "provider", "worker", "instanceType")
+				return unstructured.SetNestedField(u.Object, "m5.large", "spec", "provider", "worker", "instanceTypes")
+			},
+		},
+		{
+			name: "case 1: moved field",
+			file: "v1alpha3/awscluster.yaml",
+			obj:  &awsv1alpha2.AWSCluster{},
+			modify: func(u unstructured.Unstructured) error {
+				unstructured.RemoveNestedField(u.Object, "spec", "provider", "pods")
+				return unstructured.SetNestedField(u.Object, "10.2.0.0/16", "spec", "provider", "network", "pods", "cidrBlock")
+			},
+		},
+		{
+			name: "case 2: new field",
+			file: "v1alpha3/awscontrolplane.yaml",
+			obj:  &awsv1alpha2.AWSControlPlane{},
+			modify: func(u unstructured.Unstructured) error {
+				return unstructured.SetNestedField(u.Object, map[string]interface{}{"networkPool": "pool0"}, "spec", "nodes")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			u := readUnstructured(t, tc.file)
+			err := tc.modify(u)
+			if err != nil {
+				t.Fatalf("modify() error = %v", err)
+			}
+
+			err = fromUnstructured(u, tc.obj)
+			if !IsExecutionFailed(err) {
+				t.Fatalf("fromUnstructured() error = %v, want execution failed error", err)
+			}
+		})
+	}
+}
+
+func Test_fromUnstructured_ZeroValues(t *testing.T) {
+	u := readUnstructured(t, "v1alpha3/awscluster.yaml")
+	// Unknown fields with zero values are left out when encoding, they do
+	// not carry any information.
+	err := unstructured.SetNestedField(u.Object, map[string]interface{}{"networkPool": ""}, "spec", "provider", "nodes")
+	if err != nil {
+		t.Fatalf("SetNestedField() error = %v", err)
+	}
+
+	err = fromUnstructured(u, &awsv1alpha2.AWSCluster{})
+	if err != nil {
+		t.Fatalf("fromUnstructured() error = %v", err)
+	}
+}
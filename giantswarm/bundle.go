@@ -0,0 +1,133 @@
+package giantswarm
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	"filippo.io/age"
+	"github.com/giantswarm/microerror"
+)
+
+// bundleVersion is the version of the bundle format. It has to be increased
+// whenever fields are added to GSClusterCrs or change, since older bundles
+// would silently lack them. Bundles of other versions are rejected and have to
+// be exported again.
+const bundleVersion = 3
+
+// bundle is the content of an exported bundle. It is stored as gzipped JSON
+// encrypted with age, since it contains the cluster certificates and the CA
+// key.
+type bundle struct {
+	Version   int           `json:"version"`
+	ClusterID string        `json:"clusterID"`
+	CreatedAt time.Time     `json:"createdAt"`
+	Crs       *GSClusterCrs `json:"crs"`
+}
+
+// WriteBundle writes crs encrypted to the given age recipients to path, so
+// the cluster can later be transformed without access to the GS management
+// cluster, Vault or AWS.
+func WriteBundle(path string, crs *GSClusterCrs, recipients []string) error {
+	if len(recipients) == 0 {
+		return microerror.Maskf(invalidBundleError, "at least one age recipient is required")
+	}
+
+	var rs []age.Recipient
+	for _, r := range recipients {
+		ar, err := age.ParseX25519Recipient(r)
+		if err != nil {
+			return microerror.Maskf(invalidBundleError, "invalid age recipient %q: %s", r, err)
+		}
+		rs = append(rs, ar)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	defer f.Close()
+
+	ew, err := age.Encrypt(f, rs...)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	zw := gzip.NewWriter(ew)
+
+	b := bundle{
+		Version:   bundleVersion,
+		ClusterID: crs.AWSCluster.Name,
+		CreatedAt: time.Now().UTC(),
+		Crs:       crs,
+	}
+	err = json.NewEncoder(zw).Encode(b)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	// The writers have to be closed in order, the age writer only writes the
+	// last chunk on close.
+	err = zw.Close()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	err = ew.Close()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return microerror.Mask(f.Close())
+}
+
+// ReadBundle reads the bundle of the given cluster written by WriteBundle.
+func ReadBundle(path string, clusterID string, identities []age.Identity) (*GSClusterCrs, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	defer f.Close()
+
+	b, err := decodeBundle(f, identities)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if b.Version != bundleVersion {
+		return nil, microerror.Maskf(invalidBundleError, "bundle version %d is not supported, expected %d, export the bundle again", b.Version, bundleVersion)
+	}
+	if b.ClusterID != clusterID {
+		return nil, microerror.Maskf(invalidBundleError, "bundle belongs to cluster %q, not %q", b.ClusterID, clusterID)
+	}
+	if b.Crs == nil || b.Crs.AWSCluster == nil || b.Crs.Release == nil || b.Crs.Network == nil {
+		return nil, microerror.Maskf(invalidBundleError, "bundle is incomplete")
+	}
+
+	return b.Crs, nil
+}
+
+func decodeBundle(r io.Reader, identities []age.Identity) (*bundle, error) {
+	if len(identities) == 0 {
+		return nil, microerror.Maskf(invalidBundleError, "at least one age identity is required")
+	}
+
+	dr, err := age.Decrypt(r, identities...)
+	if err != nil {
+		return nil, microerror.Maskf(invalidBundleError, "failed to decrypt bundle: %s", err)
+	}
+
+	zr, err := gzip.NewReader(dr)
+	if err != nil {
+		return nil, microerror.Maskf(invalidBundleError, "failed to decompress bundle: %s", err)
+	}
+	defer zr.Close()
+
+	var b bundle
+	err = json.NewDecoder(zr).Decode(&b)
+	if err != nil {
+		return nil, microerror.Maskf(invalidBundleError, "failed to decode bundle: %s", err)
+	}
+
+	return &b, nil
+}
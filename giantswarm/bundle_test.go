@@ -0,0 +1,143 @@
+package giantswarm
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	awsv1alpha2 "github.com/giantswarm/apiextensions/pkg/apis/infrastructure/v1alpha2"
+	releasev1alpha1 "github.com/giantswarm/apiextensions/pkg/apis/release/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_ReadBundle(t *testing.T) {
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("age.GenerateX25519Identity() error = %v", err)
+	}
+
+	testCases := []struct {
+		name      string
+		version   int
+		clusterID string
+		crs       *GSClusterCrs
+		match     func(error) bool
+	}{
+		{
+			name:      "case 0: current version",
+			version:   bundleVersion,
+			clusterID: "abc12",
+			crs:       newBundleCrs(),
+		},
+		{
+			name:      "case 1: older version without the newer fields",
+			version:   bundleVersion - 1,
+			clusterID: "abc12",
+			crs:       newBundleCrs(),
+			match:     IsInvalidBundle,
+		},
+		{
+			name:      "case 2: other cluster",
+			version:   bundleVersion,
+			clusterID: "xyz89",
+			crs:       newBundleCrs(),
+			match:     IsInvalidBundle,
+		},
+		{
+			name:      "case 3: incomplete",
+			version:   bundleVersion,
+			clusterID: "abc12",
+			crs:       &GSClusterCrs{},
+			match:     IsInvalidBundle,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "abc12.bundle")
+			writeTestBundle(t, path, bundle{
+				Version:   tc.version,
+				ClusterID: tc.clusterID,
+				CreatedAt: time.Now().UTC(),
+				Crs:       tc.crs,
+			}, id.Recipient())
+
+			crs, err := ReadBundle(path, "abc12", []age.Identity{id})
+			switch {
+			case tc.match == nil && err != nil:
+				t.Fatalf("ReadBundle() error = %v", err)
+			case tc.match != nil && !tc.match(err):
+				t.Fatalf("ReadBundle() error = %v, want a matching error", err)
+			case tc.match == nil && crs.AWSCluster.Name != "abc12":
+				t.Fatalf("ReadBundle() read cluster %q, want %q", crs.AWSCluster.Name, "abc12")
+			}
+		})
+	}
+}
+
+func Test_WriteBundle_Version(t *testing.T) {
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("age.GenerateX25519Identity() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "abc12.bundle")
+
+	err = WriteBundle(path, newBundleCrs(), []string{id.Recipient().String()})
+	if err != nil {
+		t.Fatalf("WriteBundle() error = %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("os.Open() error = %v", err)
+	}
+	defer f.Close()
+
+	b, err := decodeBundle(f, []age.Identity{id})
+	if err != nil {
+		t.Fatalf("decodeBundle() error = %v", err)
+	}
+	if b.Version != bundleVersion {
+		t.Fatalf("bundle version = %d, want %d", b.Version, bundleVersion)
+	}
+}
+
+func newBundleCrs() *GSClusterCrs {
+	return &GSClusterCrs{
+		AWSCluster: &awsv1alpha2.AWSCluster{ObjectMeta: metav1.ObjectMeta{Name: "abc12"}},
+		Release:    &releasev1alpha1.Release{},
+		Network:    &Network{},
+	}
+}
+
+func writeTestBundle(t *testing.T, path string, b bundle, recipient age.Recipient) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("os.Create() error = %v", err)
+	}
+	defer f.Close()
+
+	ew, err := age.Encrypt(f, recipient)
+	if err != nil {
+		t.Fatalf("age.Encrypt() error = %v", err)
+	}
+	zw := gzip.NewWriter(ew)
+	err = json.NewEncoder(zw).Encode(b)
+	if err != nil {
+		t.Fatalf("json.Encode() error = %v", err)
+	}
+	err = zw.Close()
+	if err != nil {
+		t.Fatalf("gzip.Close() error = %v", err)
+	}
+	err = ew.Close()
+	if err != nil {
+		t.Fatalf("age.Close() error = %v", err)
+	}
+}
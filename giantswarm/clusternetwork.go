@@ -0,0 +1,221 @@
+package giantswarm
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	clusterValuesKey = "values"
+
+	apiServerSelector        = "k8s-app=api-server"
+	serviceClusterIPRangeArg = "--service-cluster-ip-range="
+	corednsConfigMap         = "coredns"
+	corefileKey              = "Corefile"
+)
+
+// ClusterNetwork holds the in-cluster network of the cluster, which is not
+// part of the GS CRs. It has to be kept on migration, since the service IPs
+// and the DNS names of all workloads depend on it.
+type ClusterNetwork struct {
+	// ServiceCIDR is the service cluster IP range of the apiserver, e.g.
+	// 172.31.0.0/16.
+	ServiceCIDR string `json:"serviceCIDR"`
+	// ClusterDomain is the DNS domain of the services, e.g. cluster.local.
+	ClusterDomain string `json:"clusterDomain"`
+	// DNSIP is the service IP of the cluster DNS, when known.
+	DNSIP string `json:"dnsIP,omitempty"`
+}
+
+// Validate checks that the service CIDR and the cluster domain are set and
+// that the DNS IP is part of the service CIDR.
+func (n *ClusterNetwork) Validate() error {
+	if n == nil || n.ServiceCIDR == "" {
+		return microerror.Maskf(invalidConfigError, "service CIDR of the cluster is unknown")
+	}
+	if n.ClusterDomain == "" {
+		return microerror.Maskf(invalidConfigError, "cluster domain of the cluster is unknown")
+	}
+
+	_, cidr, err := net.ParseCIDR(n.ServiceCIDR)
+	if err != nil {
+		return microerror.Maskf(invalidConfigError, "service CIDR %q is invalid: %s", n.ServiceCIDR, err)
+	}
+	if n.DNSIP != "" {
+		ip := net.ParseIP(n.DNSIP)
+		if ip == nil {
+			return microerror.Maskf(invalidConfigError, "DNS IP %q is invalid", n.DNSIP)
+		}
+		if !cidr.Contains(ip) {
+			return microerror.Maskf(invalidConfigError, "DNS IP %s is not part of the service CIDR %s", n.DNSIP, n.ServiceCIDR)
+		}
+	}
+
+	return nil
+}
+
+// MergeClusterNetwork combines the cluster networks read from different
+// sources. Values missing in one are taken from the other, differing values
+// are an error, since the sources are expected to describe the same cluster.
+func MergeClusterNetwork(a *ClusterNetwork, b *ClusterNetwork) (*ClusterNetwork, error) {
+	if a == nil {
+		return b, nil
+	}
+	if b == nil {
+		return a, nil
+	}
+
+	merged := &ClusterNetwork{}
+	for _, f := range []struct {
+		name   string
+		a, b   string
+		target *string
+	}{
+		{name: "service CIDR", a: a.ServiceCIDR, b: b.ServiceCIDR, target: &merged.ServiceCIDR},
+		{name: "cluster domain", a: a.ClusterDomain, b: b.ClusterDomain, target: &merged.ClusterDomain},
+		{name: "DNS IP", a: a.DNSIP, b: b.DNSIP, target: &merged.DNSIP},
+	} {
+		if f.a != "" && f.b != "" && f.a != f.b {
+			return nil, microerror.Maskf(clusterNetworkMismatchError, "%s %q does not match %q", f.name, f.a, f.b)
+		}
+		*f.target = f.a
+		if f.a == "" {
+			*f.target = f.b
+		}
+	}
+
+	return merged, nil
+}
+
+// clusterValues is the part of the values of the GS cluster we need. They
+// are written by cluster-operator for the default apps of the cluster.
+type clusterValues struct {
+	Cluster struct {
+		Kubernetes struct {
+			API struct {
+				ClusterIPRange string `json:"clusterIPRange"`
+			} `json:"API"`
+			DNS struct {
+				IP string `json:"IP"`
+			} `json:"DNS"`
+			Domain string `json:"domain"`
+		} `json:"kubernetes"`
+	} `json:"cluster"`
+	ClusterDNSIP string `json:"clusterDNSIP"`
+}
+
+// fetchClusterValues reads the cluster network from the <cluster ID>-cluster-values
+// ConfigMap on the GS management cluster. It returns nil when the ConfigMap
+// does not exist.
+func fetchClusterValues(c *kubernetes.Clientset, namespace string, clusterID string) (*ClusterNetwork, error) {
+	name := fmt.Sprintf("%s-cluster-values", clusterID)
+
+	cm, err := c.CoreV1().ConfigMaps(namespace).Get(name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) && namespace != defaultNamespace {
+		cm, err = c.CoreV1().ConfigMaps(defaultNamespace).Get(name, metav1.GetOptions{})
+	}
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var values clusterValues
+	err = yaml.Unmarshal([]byte(cm.Data[clusterValuesKey]), &values)
+	if err != nil {
+		return nil, microerror.Maskf(executionFailedError, "ConfigMap %s/%s has invalid values: %s", cm.Namespace, cm.Name, err)
+	}
+
+	n := &ClusterNetwork{
+		ServiceCIDR:   values.Cluster.Kubernetes.API.ClusterIPRange,
+		ClusterDomain: values.Cluster.Kubernetes.Domain,
+		DNSIP:         values.Cluster.Kubernetes.DNS.IP,
+	}
+	if n.DNSIP == "" {
+		n.DNSIP = values.ClusterDNSIP
+	}
+
+	return n, nil
+}
+
+// FetchWorkloadClusterNetwork reads the cluster network from the running
+// workload cluster: the service CIDR from the flags of the apiserver, the
+// cluster domain from the CoreDNS configuration and the DNS IP from the
+// kube-dns Service.
+func FetchWorkloadClusterNetwork(clientConfig ClientConfig) (*ClusterNetwork, error) {
+	c, err := K8sClient(clientConfig)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	pods, err := c.CoreV1().Pods(metav1.NamespaceSystem).List(metav1.ListOptions{LabelSelector: apiServerSelector})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	n := &ClusterNetwork{}
+	for _, p := range pods.Items {
+		cidr := serviceClusterIPRange(p)
+		if cidr == "" {
+			continue
+		}
+		if n.ServiceCIDR != "" && n.ServiceCIDR != cidr {
+			return nil, microerror.Maskf(clusterNetworkMismatchError, "apiservers use different service CIDRs %q and %q", n.ServiceCIDR, cidr)
+		}
+		n.ServiceCIDR = cidr
+	}
+	if n.ServiceCIDR == "" {
+		return nil, microerror.Maskf(notFoundError, "no apiserver pod with %s found in %s matching %s", serviceClusterIPRangeArg, metav1.NamespaceSystem, apiServerSelector)
+	}
+
+	cm, err := c.CoreV1().ConfigMaps(metav1.NamespaceSystem).Get(corednsConfigMap, metav1.GetOptions{})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	n.ClusterDomain = corefileDomain(cm.Data[corefileKey])
+	if n.ClusterDomain == "" {
+		return nil, microerror.Maskf(notFoundError, "no kubernetes plugin found in ConfigMap %s/%s", metav1.NamespaceSystem, corednsConfigMap)
+	}
+
+	s, err := c.CoreV1().Services(metav1.NamespaceSystem).Get("kube-dns", metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, microerror.Mask(err)
+	} else if err == nil {
+		n.DNSIP = s.Spec.ClusterIP
+	}
+
+	return n, nil
+}
+
+func serviceClusterIPRange(p v1.Pod) string {
+	for _, c := range p.Spec.Containers {
+		for _, arg := range append(append([]string{}, c.Command...), c.Args...) {
+			if strings.HasPrefix(arg, serviceClusterIPRangeArg) {
+				return strings.TrimPrefix(arg, serviceClusterIPRangeArg)
+			}
+		}
+	}
+
+	return ""
+}
+
+// corefileDomain returns the first zone of the kubernetes plugin in the
+// Corefile, e.g. "cluster.local" of "kubernetes cluster.local in-addr.arpa".
+func corefileDomain(corefile string) string {
+	for _, line := range strings.Split(corefile, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 1 && fields[0] == "kubernetes" {
+			return strings.TrimSuffix(fields[1], ".")
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,57 @@
+package giantswarm
+
+import "github.com/giantswarm/microerror"
+
+var notFoundError = &microerror.Error{
+	Kind: "notFoundError",
+}
+
+// IsNotFound asserts notFoundError.
+func IsNotFound(err error) bool {
+	return microerror.Cause(err) == notFoundError
+}
+
+var invalidBundleError = &microerror.Error{
+	Kind: "invalidBundleError",
+}
+
+// IsInvalidBundle asserts invalidBundleError.
+func IsInvalidBundle(err error) bool {
+	return microerror.Cause(err) == invalidBundleError
+}
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var restAPIError = &microerror.Error{
+	Kind: "restAPIError",
+}
+
+// IsRESTAPI asserts restAPIError.
+func IsRESTAPI(err error) bool {
+	return microerror.Cause(err) == restAPIError
+}
+
+var executionFailedError = &microerror.Error{
+	Kind: "executionFailedError",
+}
+
+// IsExecutionFailed asserts executionFailedError.
+func IsExecutionFailed(err error) bool {
+	return microerror.Cause(err) == executionFailedError
+}
+
+var clusterNetworkMismatchError = &microerror.Error{
+	Kind: "clusterNetworkMismatchError",
+}
+
+// IsClusterNetworkMismatch asserts clusterNetworkMismatchError.
+func IsClusterNetworkMismatch(err error) bool {
+	return microerror.Cause(err) == clusterNetworkMismatchError
+}
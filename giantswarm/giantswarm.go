@@ -2,14 +2,18 @@ package giantswarm
 
 import (
 	"fmt"
+	"strings"
+
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
-	"os"
-	"path/filepath"
+	"k8s.io/client-go/rest"
 
 	awsv1alpha2 "github.com/giantswarm/apiextensions/pkg/apis/infrastructure/v1alpha2"
+	releasev1alpha1 "github.com/giantswarm/apiextensions/pkg/apis/release/v1alpha1"
 	"github.com/giantswarm/apiextensions/pkg/clientset/versioned"
 	"github.com/giantswarm/microerror"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/clientcmd"
 	corev1alpha2 "sigs.k8s.io/cluster-api/api/v1alpha2"
@@ -17,132 +21,273 @@ import (
 
 const (
 	defaultNamespace = "default"
+
+	labelCluster        = "giantswarm.io/cluster"
+	labelReleaseVersion = "release.giantswarm.io/version"
 )
 
 type GSClusterCrs struct {
-	Cluster               *corev1alpha2.Cluster
-	AWSCluster            *awsv1alpha2.AWSCluster
-	AWSControlPlane       *awsv1alpha2.AWSControlPlane
-	AWSMachineDeployments []*awsv1alpha2.AWSMachineDeployment
-	G8sControlPlane       *awsv1alpha2.G8sControlPlane
-
-	EtcdCerts      *v1.Secret
-	SACerts        *v1.Secret
-	EncryptionKey  *v1.Secret
-	KubeproxyCerts *v1.Secret
+	// Namespace is the namespace the cluster CRs were found in on the GS
+	// management cluster, e.g. "default" or "org-<name>".
+	Namespace string `json:"namespace"`
+	// APIVersion is the version of the GS infrastructure CRs on the GS
+	// management cluster, e.g. "v1alpha2". The CRs of all versions are
+	// normalized into the v1alpha2 types.
+	APIVersion string `json:"apiVersion,omitempty"`
+
+	Cluster               *corev1alpha2.Cluster               `json:"cluster,omitempty"`
+	AWSCluster            *awsv1alpha2.AWSCluster             `json:"awsCluster"`
+	AWSControlPlane       *awsv1alpha2.AWSControlPlane        `json:"awsControlPlane"`
+	AWSMachineDeployments []*awsv1alpha2.AWSMachineDeployment `json:"awsMachineDeployments"`
+	G8sControlPlane       *awsv1alpha2.G8sControlPlane        `json:"g8sControlPlane"`
+	// Release is the GS release of the cluster, given by its
+	// release.giantswarm.io/version label.
+	Release *releasev1alpha1.Release `json:"release,omitempty"`
+
+	EtcdCerts      *v1.Secret `json:"etcdCerts"`
+	SACerts        *v1.Secret `json:"saCerts"`
+	EncryptionKey  *v1.Secret `json:"encryptionKey"`
+	KubeproxyCerts *v1.Secret `json:"kubeproxyCerts"`
+
+	// VaultCAKey is the private key of the cluster CA, kept in Vault.
+	VaultCAKey string `json:"vaultCAKey"`
+	// Network holds the AWS resources of the cluster discovered in AWS.
+	Network *Network `json:"network"`
+	// ClusterNetwork is the service CIDR and cluster domain of the cluster.
+	// It is nil when the source does not know them.
+	ClusterNetwork *ClusterNetwork `json:"clusterNetwork,omitempty"`
+	// NodePoolNodes are the labels and taints of the nodes of each node pool,
+	// keyed by the name of the AWSMachineDeployment. They are only known
+	// when read from the running workload cluster.
+	NodePoolNodes map[string]NodePoolNodes `json:"nodePoolNodes,omitempty"`
 }
 
-func FetchCrs(clusterID string) (*GSClusterCrs, error) {
-	gsClient, err := ApiClient()
+func FetchCrs(clusterID string, clientConfig ClientConfig) (*GSClusterCrs, error) {
+	config, err := restConfig(clientConfig)
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
-	awsCluster, err := gsClient.InfrastructureV1alpha2().AWSClusters(defaultNamespace).Get(clusterID, metav1.GetOptions{})
+
+	gsClient, err := versioned.NewForConfig(config)
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
-
-	awsCPs, err := gsClient.InfrastructureV1alpha2().AWSControlPlanes(defaultNamespace).List(metav1.ListOptions{
-		LabelSelector: fmt.Sprintf("giantswarm.io/cluster=%s", clusterID),
-	})
+	k8sClient, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
-	if len(awsCPs.Items) != 1 {
-		return nil, microerror.Maskf(nil, "expected 1 AWSControlPlane but got %d for cluster id %s", len(awsCPs.Items), clusterID)
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, microerror.Mask(err)
 	}
 
-	g8scp, err := gsClient.InfrastructureV1alpha2().G8sControlPlanes(defaultNamespace).List(metav1.ListOptions{
-		LabelSelector: fmt.Sprintf("giantswarm.io/cluster=%s", clusterID),
-	})
+	apiVersion, err := DetectAPIVersion(k8sClient.Discovery())
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
-	if len(g8scp.Items) != 1 {
-		return nil, microerror.Maskf(nil, "expected 1 G8sControlPlane but got %d for cluster id %s", len(g8scp.Items), clusterID)
+	r := &crReader{
+		client:     dynamicClient,
+		apiVersion: apiVersion,
 	}
 
-	awsMDs, err := gsClient.InfrastructureV1alpha2().AWSMachineDeployments(defaultNamespace).List(metav1.ListOptions{
-		LabelSelector: fmt.Sprintf("giantswarm.io/cluster=%s", clusterID),
-	})
+	awsCluster, err := findAWSCluster(r, clusterID)
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
+	namespace := awsCluster.Namespace
 
 	crs := &GSClusterCrs{
+		Namespace:       namespace,
+		APIVersion:      apiVersion,
 		AWSCluster:      awsCluster,
-		AWSControlPlane: &awsCPs.Items[0],
-		G8sControlPlane: &g8scp.Items[0],
+		AWSControlPlane: &awsv1alpha2.AWSControlPlane{},
+		G8sControlPlane: &awsv1alpha2.G8sControlPlane{},
 	}
 
-	for _, md := range awsMDs.Items {
-		crs.AWSMachineDeployments = append(crs.AWSMachineDeployments, &md)
+	err = r.listOne(kindAWSControlPlane, namespace, clusterID, crs.AWSControlPlane)
+	if err != nil {
+		return nil, microerror.Mask(err)
 	}
 
-	c, err := K8sClient()
+	err = r.listOne(kindG8sControlPlane, namespace, clusterID, crs.G8sControlPlane)
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
 
-	s, err := c.CoreV1().Secrets(defaultNamespace).Get(fmt.Sprintf("%s-etcd1", clusterID), metav1.GetOptions{})
+	awsMDs, err := r.list(kindAWSMachineDeployment, namespace, clusterID)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	for _, u := range awsMDs {
+		md := &awsv1alpha2.AWSMachineDeployment{}
+		err = fromUnstructured(u, md)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		crs.AWSMachineDeployments = append(crs.AWSMachineDeployments, md)
+	}
+
+	crs.Release, err = getRelease(gsClient, awsCluster.Labels[labelReleaseVersion])
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	s, err := getSecret(k8sClient, namespace, fmt.Sprintf("%s-etcd1", clusterID))
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
 	crs.EtcdCerts = s
 
-	e, err := c.CoreV1().Secrets(defaultNamespace).Get(fmt.Sprintf("%s-encryption", clusterID), metav1.GetOptions{})
+	e, err := getSecret(k8sClient, namespace, fmt.Sprintf("%s-encryption", clusterID))
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
 	crs.EncryptionKey = e
 
-	k, err := c.CoreV1().Secrets(defaultNamespace).Get(fmt.Sprintf("%s-worker", clusterID), metav1.GetOptions{})
+	k, err := getSecret(k8sClient, namespace, fmt.Sprintf("%s-worker", clusterID))
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
 	crs.KubeproxyCerts = k
 
-	sa, err := c.CoreV1().Secrets(defaultNamespace).Get(fmt.Sprintf("%s-service-account", clusterID), metav1.GetOptions{})
+	sa, err := getSecret(k8sClient, namespace, fmt.Sprintf("%s-service-account", clusterID))
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
 	crs.SACerts = sa
 
+	crs.ClusterNetwork, err = fetchClusterValues(k8sClient, namespace, clusterID)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
 	return crs, nil
 }
 
-func ApiClient() (*versioned.Clientset, error) {
-	home, exists := os.LookupEnv("HOME")
-	if !exists {
-		home = "/root"
+// findAWSCluster looks up the AWSCluster of the cluster in all namespaces,
+// since clusters of newer GS installations live in organization namespaces.
+func findAWSCluster(r *crReader, clusterID string) (*awsv1alpha2.AWSCluster, error) {
+	l, err := r.list(kindAWSCluster, metav1.NamespaceAll, clusterID)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var found []*awsv1alpha2.AWSCluster
+	for _, u := range l {
+		if u.GetName() != clusterID {
+			continue
+		}
+
+		c := &awsv1alpha2.AWSCluster{}
+		err = fromUnstructured(u, c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		found = append(found, c)
+	}
+
+	if len(found) == 0 {
+		return nil, microerror.Maskf(notFoundError, "AWSCluster with label %s not found in any namespace", clusterSelector(clusterID))
+	}
+	if len(found) > 1 {
+		var namespaces []string
+		for _, c := range found {
+			namespaces = append(namespaces, c.Namespace)
+		}
+		return nil, microerror.Maskf(executionFailedError, "found AWSCluster %s in %d namespaces %v but expected 1", clusterID, len(found), namespaces)
+	}
+
+	return found[0], nil
+}
+
+// getRelease returns the cluster scoped Release CR of the given version, which
+// is named like the version with a "v" prefix.
+func getRelease(gsClient *versioned.Clientset, version string) (*releasev1alpha1.Release, error) {
+	if version == "" {
+		return nil, microerror.Maskf(notFoundError, "AWSCluster has no %s label", labelReleaseVersion)
 	}
 
-	configPath := filepath.Join(home, ".kube", "config")
+	r, err := gsClient.ReleaseV1alpha1().Releases().Get(releaseName(version), metav1.GetOptions{})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
 
-	config, err := clientcmd.BuildConfigFromFlags("", configPath)
+	return r, nil
+}
+
+func releaseName(version string) string {
+	return fmt.Sprintf("v%s", strings.TrimPrefix(version, "v"))
+}
+
+// getSecret returns the secret from the cluster namespace. The certificates of
+// clusters in organization namespaces are still kept in the default
+// namespace, so it is used as a fallback.
+func getSecret(c *kubernetes.Clientset, namespace string, name string) (*v1.Secret, error) {
+	s, err := c.CoreV1().Secrets(namespace).Get(name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) && namespace != defaultNamespace {
+		s, err = c.CoreV1().Secrets(defaultNamespace).Get(name, metav1.GetOptions{})
+	}
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
 
-	client, err := versioned.NewForConfig(config)
+	return s, nil
+}
 
-	return client, nil
+func clusterSelector(clusterID string) string {
+	return fmt.Sprintf("%s=%s", labelCluster, clusterID)
+}
+
+// ClientConfig defines how to reach the GS management cluster.
+type ClientConfig struct {
+	// Kubeconfig is the path of the kubeconfig file. When empty, $KUBECONFIG
+	// or $HOME/.kube/config is used.
+	Kubeconfig string
+	// Context is the kubeconfig context. When empty, the current context is
+	// used.
+	Context string
 }
 
-func K8sClient() (*kubernetes.Clientset, error) {
-	home, exists := os.LookupEnv("HOME")
-	if !exists {
-		home = "/root"
+func ApiClient(c ClientConfig) (*versioned.Clientset, error) {
+	config, err := restConfig(c)
+	if err != nil {
+		return nil, microerror.Mask(err)
 	}
 
-	configPath := filepath.Join(home, ".kube", "config")
+	client, err := versioned.NewForConfig(config)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return client, nil
+}
 
-	config, err := clientcmd.BuildConfigFromFlags("", configPath)
+func K8sClient(c ClientConfig) (*kubernetes.Clientset, error) {
+	config, err := restConfig(c)
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
 
 	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
 
 	return client, nil
 }
+
+func restConfig(c ClientConfig) (*rest.Config, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = c.Kubeconfig
+
+	overrides := &clientcmd.ConfigOverrides{
+		CurrentContext: c.Context,
+	}
+
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return config, nil
+}
@@ -0,0 +1,262 @@
+package giantswarm
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/giantswarm/microerror"
+)
+
+const (
+	awsTagSubnetType      = "giantswarm.io/subnet-type"
+	awsSubnetTypePublic   = "public"
+	awsSubnetTypeCNI      = "aws-cni"
+	awsTagMD              = "giantswarm.io/machine-deployment"
+	awsTagStack           = "giantswarm.io/stack"
+	awsStackTCCP          = "tccp"
+	vpcIDFilter           = "vpc-id"
+	attachmentVPCIDFilter = "attachment.vpc-id"
+)
+
+// Network holds the AWS resources of the cluster which are not part of the
+// GS CRs and have to be looked up in the AWS account of the cluster.
+type Network struct {
+	InternetGatewayID string `json:"internetGatewayID"`
+	// Subnets are the private and public subnets of the control plane. The
+	// CNI subnets are left out.
+	Subnets               []Subnet `json:"subnets"`
+	MasterSecurityGroupID string   `json:"masterSecurityGroupID"`
+	// NodePools holds the network of each node pool, keyed by the name of
+	// the AWSMachineDeployment.
+	NodePools map[string]NodePoolNetwork `json:"nodePools"`
+	// Tags are the AWS tags of the VPC of the cluster, which GS puts on all
+	// resources of the cluster.
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+type Subnet struct {
+	ID               string `json:"id"`
+	CIDR             string `json:"cidr"`
+	AvailabilityZone string `json:"availabilityZone"`
+	IsPublic         bool   `json:"isPublic"`
+}
+
+type NodePoolNetwork struct {
+	SecurityGroupID string   `json:"securityGroupID"`
+	Subnets         []Subnet `json:"subnets"`
+	// Tags are the AWS tags of the security group of the node pool, which GS
+	// puts on all resources of the node pool.
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// DiscoverNetwork looks up the network of the cluster in AWS. When the
+// AWSCluster has no VPC in its status, e.g. because it was built from the GS
+// REST API, the VPC is looked up by its tags and written to the status.
+func DiscoverNetwork(crs *GSClusterCrs) (*Network, error) {
+	clusterID := crs.AWSCluster.Name
+
+	sess, err := getAWSSession(crs.AWSCluster.Spec.Provider.Region)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	ec2Client := ec2.New(sess)
+
+	if crs.AWSCluster.Status.Provider.Network.VPCID == "" {
+		vpcID, cidr, err := fetchClusterVPC(ec2Client, clusterID)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		crs.AWSCluster.Status.Provider.Network.VPCID = vpcID
+		crs.AWSCluster.Status.Provider.Network.CIDR = cidr
+	}
+	vpcID := crs.AWSCluster.Status.Provider.Network.VPCID
+
+	n := &Network{
+		NodePools: map[string]NodePoolNetwork{},
+	}
+
+	n.InternetGatewayID, err = fetchClusterIGW(ec2Client, vpcID)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	n.Subnets, err = fetchSubnets(ec2Client, []*ec2.Filter{
+		filter(vpcIDFilter, vpcID),
+		filter("tag:"+awsTagStack, awsStackTCCP),
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	n.Tags, err = fetchVPCTags(ec2Client, vpcID)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	sg, err := fetchSecurityGroup(ec2Client, []*ec2.Filter{
+		filter("tag:Name", fmt.Sprintf("%s-master", clusterID)),
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	n.MasterSecurityGroupID = *sg.GroupId
+
+	for _, md := range crs.AWSMachineDeployments {
+		var np NodePoolNetwork
+
+		sg, err := fetchSecurityGroup(ec2Client, []*ec2.Filter{
+			filter("tag:Name", fmt.Sprintf("%s-worker", clusterID)),
+			filter("tag:"+awsTagMD, md.Name),
+		})
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		np.SecurityGroupID = *sg.GroupId
+		np.Tags = tagMap(sg.Tags)
+
+		np.Subnets, err = fetchSubnets(ec2Client, []*ec2.Filter{
+			filter("tag:"+awsTagMD, md.Name),
+		})
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		n.NodePools[md.Name] = np
+	}
+
+	return n, nil
+}
+
+// fetchSubnets returns the subnets matching the filters, ignoring CNI
+// subnets since only private and public subnets are added to the CRs.
+func fetchSubnets(ec2Client *ec2.EC2, filters []*ec2.Filter) ([]Subnet, error) {
+	o, err := ec2Client.DescribeSubnets(&ec2.DescribeSubnetsInput{Filters: filters})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var subnets []Subnet
+	for _, subnet := range o.Subnets {
+		if isCNISubnet(subnet.Tags) {
+			continue
+		}
+
+		subnets = append(subnets, Subnet{
+			ID:               *subnet.SubnetId,
+			CIDR:             *subnet.CidrBlock,
+			AvailabilityZone: *subnet.AvailabilityZone,
+			IsPublic:         isPublicNetwork(subnet.Tags),
+		})
+	}
+
+	return subnets, nil
+}
+
+func fetchSecurityGroup(ec2Client *ec2.EC2, filters []*ec2.Filter) (*ec2.SecurityGroup, error) {
+	o, err := ec2Client.DescribeSecurityGroups(&ec2.DescribeSecurityGroupsInput{Filters: filters})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if len(o.SecurityGroups) != 1 {
+		return nil, microerror.Maskf(executionFailedError, "expected 1 security group but found %d", len(o.SecurityGroups))
+	}
+
+	return o.SecurityGroups[0], nil
+}
+
+func fetchVPCTags(ec2Client *ec2.EC2, vpcID string) (map[string]string, error) {
+	o, err := ec2Client.DescribeVpcs(&ec2.DescribeVpcsInput{
+		VpcIds: aws.StringSlice([]string{vpcID}),
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if len(o.Vpcs) != 1 {
+		return nil, microerror.Maskf(executionFailedError, "found %d VPCs with ID %s but expected 1", len(o.Vpcs), vpcID)
+	}
+
+	return tagMap(o.Vpcs[0].Tags), nil
+}
+
+func fetchClusterVPC(ec2Client *ec2.EC2, clusterID string) (string, string, error) {
+	o, err := ec2Client.DescribeVpcs(&ec2.DescribeVpcsInput{
+		Filters: []*ec2.Filter{
+			filter("tag:"+labelCluster, clusterID),
+			filter("tag:"+awsTagStack, awsStackTCCP),
+		},
+	})
+	if err != nil {
+		return "", "", microerror.Mask(err)
+	}
+
+	if len(o.Vpcs) != 1 {
+		return "", "", microerror.Maskf(executionFailedError, "found %d VPCs of cluster %s but expected 1", len(o.Vpcs), clusterID)
+	}
+
+	return *o.Vpcs[0].VpcId, *o.Vpcs[0].CidrBlock, nil
+}
+
+func fetchClusterIGW(ec2Client *ec2.EC2, vpcID string) (string, error) {
+	o, err := ec2Client.DescribeInternetGateways(&ec2.DescribeInternetGatewaysInput{
+		Filters: []*ec2.Filter{
+			filter(attachmentVPCIDFilter, vpcID),
+		},
+	})
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	if len(o.InternetGateways) != 1 {
+		return "", microerror.Maskf(executionFailedError, "found %d internet gateways but expected 1", len(o.InternetGateways))
+	}
+
+	return *o.InternetGateways[0].InternetGatewayId, nil
+}
+
+func filter(name string, value string) *ec2.Filter {
+	return &ec2.Filter{
+		Name:   aws.String(name),
+		Values: aws.StringSlice([]string{value}),
+	}
+}
+
+func getAWSSession(region string) (*session.Session, error) {
+	awsSession, err := session.NewSession(&aws.Config{
+		Region: aws.String(region)},
+	)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	return awsSession, nil
+}
+
+func isCNISubnet(tags []*ec2.Tag) bool {
+	for _, tag := range tags {
+		if *tag.Key == awsTagSubnetType && *tag.Value == awsSubnetTypeCNI {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isPublicNetwork(tags []*ec2.Tag) bool {
+	for _, tag := range tags {
+		if *tag.Key == awsTagSubnetType && *tag.Value == awsSubnetTypePublic {
+			return true
+		}
+	}
+
+	return false
+}
+
+func tagMap(tags []*ec2.Tag) map[string]string {
+	m := map[string]string{}
+	for _, tag := range tags {
+		m[*tag.Key] = *tag.Value
+	}
+
+	return m
+}
@@ -0,0 +1,86 @@
+package giantswarm
+
+import (
+	"fmt"
+
+	"github.com/giantswarm/microerror"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NodePoolNodes are the labels and taints all nodes of a node pool have in
+// common. They are read unfiltered from the nodes, so they include the ones
+// set by Kubernetes itself.
+type NodePoolNodes struct {
+	Labels map[string]string `json:"labels,omitempty"`
+	Taints []v1.Taint        `json:"taints,omitempty"`
+}
+
+// FetchWorkloadNodePools reads the labels and taints of the nodes of the
+// given node pools from the running workload cluster. Node pools without
+// nodes are left out.
+func FetchWorkloadNodePools(clientConfig ClientConfig, nodePoolIDs []string) (map[string]NodePoolNodes, error) {
+	c, err := K8sClient(clientConfig)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	nodePools := map[string]NodePoolNodes{}
+	for _, id := range nodePoolIDs {
+		nodes, err := c.CoreV1().Nodes().List(metav1.ListOptions{
+			LabelSelector: fmt.Sprintf("%s=%s", labelMachineDeployment, id),
+		})
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if len(nodes.Items) == 0 {
+			continue
+		}
+
+		nodePools[id] = commonNodes(nodes.Items)
+	}
+
+	return nodePools, nil
+}
+
+// commonNodes returns the labels and taints all nodes have.
+func commonNodes(nodes []v1.Node) NodePoolNodes {
+	n := NodePoolNodes{
+		Labels: map[string]string{},
+	}
+	for k, v := range nodes[0].Labels {
+		n.Labels[k] = v
+	}
+	for _, t := range nodes[0].Spec.Taints {
+		// The time a taint was added differs between the nodes.
+		n.Taints = append(n.Taints, v1.Taint{Key: t.Key, Value: t.Value, Effect: t.Effect})
+	}
+
+	for _, node := range nodes[1:] {
+		for k, v := range n.Labels {
+			if nv, ok := node.Labels[k]; !ok || nv != v {
+				delete(n.Labels, k)
+			}
+		}
+
+		var taints []v1.Taint
+		for _, t := range n.Taints {
+			if hasTaint(node.Spec.Taints, t) {
+				taints = append(taints, t)
+			}
+		}
+		n.Taints = taints
+	}
+
+	return n
+}
+
+func hasTaint(taints []v1.Taint, taint v1.Taint) bool {
+	for _, t := range taints {
+		if t.Key == taint.Key && t.Value == taint.Value && t.Effect == taint.Effect {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,467 @@
+package giantswarm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	awsv1alpha2 "github.com/giantswarm/apiextensions/pkg/apis/infrastructure/v1alpha2"
+	releasev1alpha1 "github.com/giantswarm/apiextensions/pkg/apis/release/v1alpha1"
+	"github.com/giantswarm/microerror"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	labelMachineDeployment = "giantswarm.io/machine-deployment"
+	labelOrganization      = "giantswarm.io/organization"
+
+	userAgent = "aws-gs-to-capi"
+)
+
+type RESTSourceConfig struct {
+	// Endpoint is the URL of the GS REST API, as used by gsctl, e.g.
+	// https://api.g8s.<installation>.<base-domain>.
+	Endpoint string
+	// Token is the GS API auth token.
+	Token string
+	// HTTPClient is used for all requests. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+	// SecretsDir is a directory with the etcd, encryption, worker and
+	// service account Secrets of the cluster, one YAML or JSON manifest per
+	// file, since the REST API does not expose them.
+	SecretsDir string
+	// PodsCIDR is the pod CIDR of the cluster, since the REST API does not
+	// expose it.
+	PodsCIDR string
+	// MasterInstanceType is the instance type of the masters of the cluster,
+	// since the REST API does not expose it.
+	MasterInstanceType string
+}
+
+// RESTSource reads the cluster from the GS REST API, the CA key from Vault and
+// the network from AWS. It is meant for teams without direct access to the
+// GS management cluster.
+type RESTSource struct {
+	endpoint   *url.URL
+	token      string
+	httpClient *http.Client
+	secretsDir string
+	podsCIDR   string
+
+	masterInstanceType string
+}
+
+func NewRESTSource(config RESTSourceConfig) (*RESTSource, error) {
+	if config.Endpoint == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Endpoint must not be empty", config)
+	}
+	if config.Token == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Token must not be empty", config)
+	}
+	if config.SecretsDir == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.SecretsDir must not be empty", config)
+	}
+	if config.PodsCIDR == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.PodsCIDR must not be empty", config)
+	}
+	if config.MasterInstanceType == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.MasterInstanceType must not be empty", config)
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+
+	u, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Endpoint is invalid: %s", config, err)
+	}
+
+	s := &RESTSource{
+		endpoint:   u,
+		token:      config.Token,
+		httpClient: config.HTTPClient,
+		secretsDir: config.SecretsDir,
+		podsCIDR:   config.PodsCIDR,
+
+		masterInstanceType: config.MasterInstanceType,
+	}
+
+	return s, nil
+}
+
+func (s *RESTSource) Fetch(clusterID string) (*GSClusterCrs, error) {
+	crs, err := s.FetchCrs(clusterID)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	err = complete(crs)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return crs, nil
+}
+
+// FetchCrs builds the GS CRs of the cluster from the REST API and reads the
+// secrets from the secrets directory. Unlike Fetch it neither talks to Vault
+// nor to AWS, so VaultCAKey and Network are not set.
+func (s *RESTSource) FetchCrs(clusterID string) (*GSClusterCrs, error) {
+	var info restInfo
+	err := s.get("/v4/info/", &info)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var cluster restCluster
+	err = s.get(fmt.Sprintf("/v5/clusters/%s/", clusterID), &cluster)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var nodePools []restNodePool
+	err = s.get(fmt.Sprintf("/v5/clusters/%s/nodepools/", clusterID), &nodePools)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var releases []restRelease
+	err = s.get("/v4/releases/", &releases)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	release, err := findRelease(releases, cluster.ReleaseVersion)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	domain, err := dnsDomain(cluster.APIEndpoint, clusterID)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	labels := map[string]string{}
+	for k, v := range cluster.Labels {
+		labels[k] = v
+	}
+	labels[labelCluster] = clusterID
+	labels[labelOrganization] = cluster.Owner
+	labels[labelReleaseVersion] = cluster.ReleaseVersion
+
+	meta := func(name string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Name:      name,
+			Namespace: defaultNamespace,
+			Labels:    copyLabels(labels),
+		}
+	}
+
+	var azs []string
+	replicas := 1
+	if cluster.MasterNodes != nil {
+		azs = cluster.MasterNodes.AvailabilityZones
+		if cluster.MasterNodes.HighAvailability {
+			replicas = 3
+		}
+	} else if cluster.Master != nil {
+		azs = []string{cluster.Master.AvailabilityZone}
+	}
+	if len(azs) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "cluster %s has no master availability zones", clusterID)
+	}
+
+	crs := &GSClusterCrs{
+		Namespace: defaultNamespace,
+		AWSCluster: &awsv1alpha2.AWSCluster{
+			ObjectMeta: meta(clusterID),
+			Spec: awsv1alpha2.AWSClusterSpec{
+				Cluster: awsv1alpha2.AWSClusterSpecCluster{
+					Description: cluster.Name,
+					DNS: awsv1alpha2.AWSClusterSpecClusterDNS{
+						Domain: domain,
+					},
+				},
+				Provider: awsv1alpha2.AWSClusterSpecProvider{
+					Master: awsv1alpha2.AWSClusterSpecProviderMaster{
+						AvailabilityZone: azs[0],
+						InstanceType:     s.masterInstanceType,
+					},
+					Pods: awsv1alpha2.AWSClusterSpecProviderPods{
+						CIDRBlock: s.podsCIDR,
+					},
+					Region: info.General.Datacenter,
+				},
+			},
+		},
+		AWSControlPlane: &awsv1alpha2.AWSControlPlane{
+			ObjectMeta: meta(clusterID),
+			Spec: awsv1alpha2.AWSControlPlaneSpec{
+				AvailabilityZones: azs,
+				InstanceType:      s.masterInstanceType,
+			},
+		},
+		G8sControlPlane: &awsv1alpha2.G8sControlPlane{
+			ObjectMeta: meta(clusterID),
+			Spec: awsv1alpha2.G8sControlPlaneSpec{
+				Replicas: replicas,
+			},
+		},
+		Release: release,
+	}
+
+	for _, np := range nodePools {
+		md := &awsv1alpha2.AWSMachineDeployment{
+			ObjectMeta: meta(np.ID),
+			Spec: awsv1alpha2.AWSMachineDeploymentSpec{
+				NodePool: awsv1alpha2.AWSMachineDeploymentSpecNodePool{
+					Description: np.Name,
+					Machine: awsv1alpha2.AWSMachineDeploymentSpecNodePoolMachine{
+						DockerVolumeSizeGB:  np.NodeSpec.VolumeSizesGB.Docker,
+						KubeletVolumeSizeGB: np.NodeSpec.VolumeSizesGB.Kubelet,
+					},
+					Scaling: awsv1alpha2.AWSMachineDeploymentSpecNodePoolScaling{
+						Max: np.Scaling.Max,
+						Min: np.Scaling.Min,
+					},
+				},
+				Provider: awsv1alpha2.AWSMachineDeploymentSpecProvider{
+					AvailabilityZones: np.AvailabilityZones,
+					InstanceDistribution: awsv1alpha2.AWSMachineDeploymentSpecInstanceDistribution{
+						OnDemandBaseCapacity:                np.NodeSpec.AWS.InstanceDistribution.OnDemandBaseCapacity,
+						OnDemandPercentageAboveBaseCapacity: np.NodeSpec.AWS.InstanceDistribution.OnDemandPercentageAboveBaseCapacity,
+					},
+					Worker: awsv1alpha2.AWSMachineDeploymentSpecProviderWorker{
+						InstanceType:          np.NodeSpec.AWS.InstanceType,
+						UseAlikeInstanceTypes: np.NodeSpec.AWS.UseAlikeInstanceTypes,
+					},
+				},
+			},
+		}
+		md.Labels[labelMachineDeployment] = np.ID
+
+		crs.AWSMachineDeployments = append(crs.AWSMachineDeployments, md)
+	}
+
+	secrets, err := readSecrets(s.secretsDir)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	for name, target := range map[string]**v1.Secret{
+		fmt.Sprintf("%s-etcd1", clusterID):           &crs.EtcdCerts,
+		fmt.Sprintf("%s-encryption", clusterID):      &crs.EncryptionKey,
+		fmt.Sprintf("%s-worker", clusterID):          &crs.KubeproxyCerts,
+		fmt.Sprintf("%s-service-account", clusterID): &crs.SACerts,
+	} {
+		secret, ok := secrets[name]
+		if !ok {
+			return nil, microerror.Maskf(notFoundError, "secret %s not found in %s", name, s.secretsDir)
+		}
+		*target = secret
+	}
+
+	return crs, nil
+}
+
+// get sends a GET request for path to the REST API and decodes the JSON
+// response into v.
+func (s *RESTSource) get(path string, v interface{}) error {
+	u := s.endpoint.ResolveReference(&url.URL{Path: path})
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("giantswarm %s", s.token))
+	req.Header.Set("User-Agent", userAgent)
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	defer res.Body.Close()
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if res.StatusCode == http.StatusNotFound {
+		return microerror.Maskf(notFoundError, "GET %s: %s", path, restErrorMessage(b))
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return microerror.Maskf(restAPIError, "GET %s returned %d: %s", path, res.StatusCode, restErrorMessage(b))
+	}
+
+	err = json.Unmarshal(b, v)
+	if err != nil {
+		return microerror.Maskf(restAPIError, "GET %s returned an invalid response: %s", path, err)
+	}
+
+	return nil
+}
+
+// findRelease builds the Release CR of the given version from the releases
+// of the REST API.
+func findRelease(releases []restRelease, version string) (*releasev1alpha1.Release, error) {
+	for _, r := range releases {
+		if r.Version != strings.TrimPrefix(version, "v") {
+			continue
+		}
+
+		release := &releasev1alpha1.Release{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: releaseName(r.Version),
+			},
+		}
+		for _, c := range r.Components {
+			release.Spec.Components = append(release.Spec.Components, releasev1alpha1.ReleaseSpecComponent{
+				Name:    c.Name,
+				Version: c.Version,
+			})
+		}
+
+		return release, nil
+	}
+
+	return nil, microerror.Maskf(notFoundError, "release %s not found", version)
+}
+
+// restErrorMessage returns the message of an error response of the REST API,
+// or the raw body if it is not in the expected format.
+func restErrorMessage(b []byte) string {
+	var e struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	err := json.Unmarshal(b, &e)
+	if err != nil || e.Message == "" {
+		return strings.TrimSpace(string(b))
+	}
+
+	return fmt.Sprintf("%s (%s)", e.Message, e.Code)
+}
+
+// dnsDomain returns the base domain of the cluster from its API endpoint,
+// which is https://api.<cluster-id>.k8s.<domain>.
+func dnsDomain(apiEndpoint string, clusterID string) (string, error) {
+	u, err := url.Parse(apiEndpoint)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	prefix := fmt.Sprintf("api.%s.k8s.", clusterID)
+	if !strings.HasPrefix(u.Hostname(), prefix) {
+		return "", microerror.Maskf(restAPIError, "unexpected API endpoint %q of cluster %s", apiEndpoint, clusterID)
+	}
+
+	return strings.TrimPrefix(u.Hostname(), prefix), nil
+}
+
+// readSecrets reads all Secret manifests in dir, keyed by name.
+func readSecrets(dir string) (map[string]*v1.Secret, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	secrets := map[string]*v1.Secret{}
+	for _, f := range files {
+		switch filepath.Ext(f.Name()) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+
+		b, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		var s v1.Secret
+		err = yaml.Unmarshal(b, &s)
+		if err != nil {
+			return nil, microerror.Maskf(invalidConfigError, "failed to read %s: %s", f.Name(), err)
+		}
+		if s.Kind != "Secret" {
+			continue
+		}
+
+		secrets[s.Name] = &s
+	}
+
+	return secrets, nil
+}
+
+func copyLabels(labels map[string]string) map[string]string {
+	c := map[string]string{}
+	for k, v := range labels {
+		c[k] = v
+	}
+
+	return c
+}
+
+type restInfo struct {
+	General struct {
+		InstallationName string `json:"installation_name"`
+		Provider         string `json:"provider"`
+		Datacenter       string `json:"datacenter"`
+	} `json:"general"`
+}
+
+type restCluster struct {
+	ID             string            `json:"id"`
+	Name           string            `json:"name"`
+	APIEndpoint    string            `json:"api_endpoint"`
+	Owner          string            `json:"owner"`
+	ReleaseVersion string            `json:"release_version"`
+	Labels         map[string]string `json:"labels"`
+	Master         *struct {
+		AvailabilityZone string `json:"availability_zone"`
+	} `json:"master"`
+	MasterNodes *struct {
+		HighAvailability  bool     `json:"high_availability"`
+		AvailabilityZones []string `json:"availability_zones"`
+	} `json:"master_nodes"`
+}
+
+type restRelease struct {
+	Version    string `json:"version"`
+	Components []struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"components"`
+}
+
+type restNodePool struct {
+	ID                string   `json:"id"`
+	Name              string   `json:"name"`
+	AvailabilityZones []string `json:"availability_zones"`
+	Scaling           struct {
+		Min int `json:"min"`
+		Max int `json:"max"`
+	} `json:"scaling"`
+	NodeSpec struct {
+		AWS struct {
+			InstanceType          string `json:"instance_type"`
+			UseAlikeInstanceTypes bool   `json:"use_alike_instance_types"`
+			InstanceDistribution  struct {
+				OnDemandBaseCapacity                int  `json:"on_demand_base_capacity"`
+				OnDemandPercentageAboveBaseCapacity *int `json:"on_demand_percentage_above_base_capacity"`
+			} `json:"instance_distribution"`
+		} `json:"aws"`
+		VolumeSizesGB struct {
+			Docker  int `json:"docker"`
+			Kubelet int `json:"kubelet"`
+		} `json:"volume_sizes_gb"`
+	} `json:"node_spec"`
+}
@@ -0,0 +1,272 @@
+package giantswarm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const (
+	testToken = "secret-token"
+
+	testInfo = `{"general":{"installation_name":"gauss","provider":"aws","datacenter":"eu-central-1"}}`
+
+	testCluster = `{
+  "id": "abc12",
+  "name": "prod cluster",
+  "api_endpoint": "https://api.abc12.k8s.gauss.eu-central-1.aws.gigantic.io",
+  "owner": "acme",
+  "release_version": "14.1.0",
+  "labels": {"team": "data"},
+  "master_nodes": {"high_availability": true, "availability_zones": ["eu-central-1a", "eu-central-1b", "eu-central-1c"]}
+}`
+
+	testNodePools = `[{
+  "id": "np001",
+  "name": "workers",
+  "availability_zones": ["eu-central-1a"],
+  "scaling": {"min": 2, "max": 5},
+  "node_spec": {
+    "aws": {
+      "instance_type": "m5.large",
+      "use_alike_instance_types": true,
+      "instance_distribution": {"on_demand_base_capacity": 1, "on_demand_percentage_above_base_capacity": 50}
+    },
+    "volume_sizes_gb": {"docker": 100, "kubelet": 50}
+  }
+}]`
+
+	testReleases = `[
+  {"version": "13.0.0", "components": [{"name": "kubernetes", "version": "1.18.12"}]},
+  {"version": "14.1.0", "components": [{"name": "kubernetes", "version": "1.19.9"}, {"name": "etcd", "version": "3.4.14"}]}
+]`
+)
+
+// newTestAPI returns a stand-in of the GS REST API serving the cluster abc12.
+func newTestAPI(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	responses := map[string]string{
+		"/v4/info/":                     testInfo,
+		"/v5/clusters/abc12/":           testCluster,
+		"/v5/clusters/abc12/nodepools/": testNodePools,
+		"/v4/releases/":                 testReleases,
+	}
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "giantswarm "+testToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"code":"PERMISSION_DENIED","message":"The requested resource cannot be accessed"}`)
+			return
+		}
+
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"code":"RESOURCE_NOT_FOUND","message":"The cluster could not be found"}`)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// newTestSecretsDir returns a directory with the Secrets of the cluster abc12.
+func newTestSecretsDir(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for _, name := range []string{"abc12-etcd1", "abc12-encryption", "abc12-worker", "abc12-service-account"} {
+		manifest := fmt.Sprintf("apiVersion: v1\nkind: Secret\nmetadata:\n  name: %s\n  namespace: default\ndata:\n  key: a2V5\n", name)
+		err := ioutil.WriteFile(filepath.Join(dir, name+".yaml"), []byte(manifest), 0600)
+		if err != nil {
+			t.Fatalf("ioutil.WriteFile() error = %v", err)
+		}
+	}
+	// Other files and kinds are ignored.
+	err := ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("not a manifest"), 0600)
+	if err != nil {
+		t.Fatalf("ioutil.WriteFile() error = %v", err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "cm.yaml"), []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: abc12-etcd1\n"), 0600)
+	if err != nil {
+		t.Fatalf("ioutil.WriteFile() error = %v", err)
+	}
+
+	return dir
+}
+
+func newTestRESTSource(t *testing.T, api *httptest.Server, token string) *RESTSource {
+	t.Helper()
+
+	s, err := NewRESTSource(RESTSourceConfig{
+		Endpoint:           api.URL,
+		Token:              token,
+		HTTPClient:         api.Client(),
+		SecretsDir:         newTestSecretsDir(t),
+		PodsCIDR:           "10.2.0.0/16",
+		MasterInstanceType: "m5.2xlarge",
+	})
+	if err != nil {
+		t.Fatalf("NewRESTSource() error = %v", err)
+	}
+
+	return s
+}
+
+func Test_RESTSource_FetchCrs(t *testing.T) {
+	s := newTestRESTSource(t, newTestAPI(t), testToken)
+
+	crs, err := s.FetchCrs("abc12")
+	if err != nil {
+		t.Fatalf("FetchCrs() error = %v", err)
+	}
+
+	labels := map[string]string{
+		"team":              "data",
+		labelCluster:        "abc12",
+		labelOrganization:   "acme",
+		labelReleaseVersion: "14.1.0",
+	}
+
+	if crs.Namespace != defaultNamespace {
+		t.Errorf("Namespace = %q, want %q", crs.Namespace, defaultNamespace)
+	}
+
+	ac := crs.AWSCluster
+	for _, c := range []struct {
+		name      string
+		got, want interface{}
+	}{
+		{name: "AWSCluster name", got: ac.Name, want: "abc12"},
+		{name: "AWSCluster labels", got: ac.Labels, want: labels},
+		{name: "description", got: ac.Spec.Cluster.Description, want: "prod cluster"},
+		{name: "DNS domain", got: ac.Spec.Cluster.DNS.Domain, want: "gauss.eu-central-1.aws.gigantic.io"},
+		{name: "region", got: ac.Spec.Provider.Region, want: "eu-central-1"},
+		{name: "pods CIDR", got: ac.Spec.Provider.Pods.CIDRBlock, want: "10.2.0.0/16"},
+		{name: "master availability zone", got: ac.Spec.Provider.Master.AvailabilityZone, want: "eu-central-1a"},
+		{name: "master instance type", got: ac.Spec.Provider.Master.InstanceType, want: "m5.2xlarge"},
+		{name: "AWSControlPlane availability zones", got: crs.AWSControlPlane.Spec.AvailabilityZones, want: []string{"eu-central-1a", "eu-central-1b", "eu-central-1c"}},
+		{name: "AWSControlPlane instance type", got: crs.AWSControlPlane.Spec.InstanceType, want: "m5.2xlarge"},
+		{name: "G8sControlPlane replicas", got: crs.G8sControlPlane.Spec.Replicas, want: 3},
+		{name: "release", got: crs.Release.Name, want: "v14.1.0"},
+		{name: "release components", got: len(crs.Release.Spec.Components), want: 2},
+		{name: "etcd certs", got: crs.EtcdCerts.Name, want: "abc12-etcd1"},
+		{name: "encryption key", got: crs.EncryptionKey.Name, want: "abc12-encryption"},
+		{name: "kube-proxy certs", got: crs.KubeproxyCerts.Name, want: "abc12-worker"},
+		{name: "service account certs", got: crs.SACerts.Name, want: "abc12-service-account"},
+	} {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s = %#v, want %#v", c.name, c.got, c.want)
+		}
+	}
+
+	if len(crs.AWSMachineDeployments) != 1 {
+		t.Fatalf("got %d AWSMachineDeployments, want 1", len(crs.AWSMachineDeployments))
+	}
+	md := crs.AWSMachineDeployments[0]
+	mdLabels := map[string]string{labelMachineDeployment: "np001"}
+	for k, v := range labels {
+		mdLabels[k] = v
+	}
+	for _, c := range []struct {
+		name      string
+		got, want interface{}
+	}{
+		{name: "AWSMachineDeployment name", got: md.Name, want: "np001"},
+		{name: "AWSMachineDeployment labels", got: md.Labels, want: mdLabels},
+		{name: "node pool description", got: md.Spec.NodePool.Description, want: "workers"},
+		{name: "scaling min", got: md.Spec.NodePool.Scaling.Min, want: 2},
+		{name: "scaling max", got: md.Spec.NodePool.Scaling.Max, want: 5},
+		{name: "docker volume", got: md.Spec.NodePool.Machine.DockerVolumeSizeGB, want: 100},
+		{name: "kubelet volume", got: md.Spec.NodePool.Machine.KubeletVolumeSizeGB, want: 50},
+		{name: "availability zones", got: md.Spec.Provider.AvailabilityZones, want: []string{"eu-central-1a"}},
+		{name: "instance type", got: md.Spec.Provider.Worker.InstanceType, want: "m5.large"},
+		{name: "alike instance types", got: md.Spec.Provider.Worker.UseAlikeInstanceTypes, want: true},
+		{name: "on-demand base capacity", got: md.Spec.Provider.InstanceDistribution.OnDemandBaseCapacity, want: 1},
+		{name: "on-demand percentage", got: *md.Spec.Provider.InstanceDistribution.OnDemandPercentageAboveBaseCapacity, want: 50},
+	} {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s = %#v, want %#v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func Test_RESTSource_FetchCrs_Errors(t *testing.T) {
+	api := newTestAPI(t)
+
+	testCases := []struct {
+		name      string
+		token     string
+		clusterID string
+		match     func(error) bool
+	}{
+		{
+			name:      "case 0: invalid token",
+			token:     "wrong-token",
+			clusterID: "abc12",
+			match:     IsRESTAPI,
+		},
+		{
+			name:      "case 1: unknown cluster",
+			token:     testToken,
+			clusterID: "zzz99",
+			match:     IsNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestRESTSource(t, api, tc.token)
+
+			_, err := s.FetchCrs(tc.clusterID)
+			if !tc.match(err) {
+				t.Fatalf("FetchCrs() error = %v, want a matching error", err)
+			}
+		})
+	}
+}
+
+func Test_NewRESTSource_Required(t *testing.T) {
+	valid := RESTSourceConfig{
+		Endpoint:           "https://api.g8s.gauss.example.com",
+		Token:              testToken,
+		SecretsDir:         "secrets",
+		PodsCIDR:           "10.2.0.0/16",
+		MasterInstanceType: "m5.xlarge",
+	}
+
+	testCases := []struct {
+		name   string
+		modify func(c *RESTSourceConfig)
+	}{
+		{name: "case 0: no endpoint", modify: func(c *RESTSourceConfig) { c.Endpoint = "" }},
+		{name: "case 1: no token", modify: func(c *RESTSourceConfig) { c.Token = "" }},
+		{name: "case 2: no secrets dir", modify: func(c *RESTSourceConfig) { c.SecretsDir = "" }},
+		{name: "case 3: no pods CIDR", modify: func(c *RESTSourceConfig) { c.PodsCIDR = "" }},
+		{name: "case 4: no master instance type", modify: func(c *RESTSourceConfig) { c.MasterInstanceType = "" }},
+	}
+
+	_, err := NewRESTSource(valid)
+	if err != nil {
+		t.Fatalf("NewRESTSource() error = %v", err)
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := valid
+			tc.modify(&c)
+
+			_, err := NewRESTSource(c)
+			if !IsInvalidConfig(err) {
+				t.Fatalf("NewRESTSource() error = %v, want invalid config error", err)
+			}
+		})
+	}
+}
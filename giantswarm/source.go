@@ -0,0 +1,97 @@
+package giantswarm
+
+import (
+	"filippo.io/age"
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/aws-gs-to-capi/vault"
+)
+
+// Source provides everything needed to transform a GS cluster into CAPI
+// CRs.
+type Source interface {
+	// Fetch returns the CRs, secrets, CA key and network of the cluster.
+	Fetch(clusterID string) (*GSClusterCrs, error)
+}
+
+// MCSource reads the cluster from the GS management cluster, the CA key from
+// Vault and the network from AWS.
+type MCSource struct {
+	clientConfig ClientConfig
+}
+
+func NewMCSource(clientConfig ClientConfig) *MCSource {
+	return &MCSource{
+		clientConfig: clientConfig,
+	}
+}
+
+func (s *MCSource) Fetch(clusterID string) (*GSClusterCrs, error) {
+	crs, err := FetchCrs(clusterID, s.clientConfig)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	err = complete(crs)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return crs, nil
+}
+
+type BundleSourceConfig struct {
+	// Path is the bundle written by WriteBundle.
+	Path string
+	// Identities are the age identities to decrypt the bundle with.
+	Identities []age.Identity
+}
+
+// BundleSource reads the cluster from a bundle written by WriteBundle, so no
+// access to the GS management cluster, Vault or AWS is needed.
+type BundleSource struct {
+	path       string
+	identities []age.Identity
+}
+
+func NewBundleSource(config BundleSourceConfig) (*BundleSource, error) {
+	if config.Path == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Path must not be empty", config)
+	}
+	if len(config.Identities) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Identities must not be empty", config)
+	}
+
+	s := &BundleSource{
+		path:       config.Path,
+		identities: config.Identities,
+	}
+
+	return s, nil
+}
+
+func (s *BundleSource) Fetch(clusterID string) (*GSClusterCrs, error) {
+	crs, err := ReadBundle(s.path, clusterID, s.identities)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return crs, nil
+}
+
+// complete adds the CA key from Vault and the network from AWS to the CRs.
+func complete(crs *GSClusterCrs) error {
+	var err error
+
+	crs.VaultCAKey, err = vault.GetVaultCAKey(crs.AWSCluster.Name)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	crs.Network, err = DiscoverNetwork(crs)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
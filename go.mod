@@ -3,6 +3,7 @@ module github.com/giantswarm/aws-gs-to-capi
 go 1.15
 
 require (
+	filippo.io/age v1.0.0
 	github.com/aws/aws-sdk-go v1.37.25
 	github.com/giantswarm/apiextensions v0.4.20
 	github.com/giantswarm/microerror v0.3.0
@@ -11,10 +12,10 @@ require (
 	github.com/onsi/ginkgo v1.14.2 // indirect
 	github.com/onsi/gomega v1.10.3 // indirect
 	github.com/prometheus/client_golang v1.8.0 // indirect
-	github.com/spf13/pflag v1.0.5
-	golang.org/x/crypto v0.0.0-20201117144127-c1f2f97bffc9 // indirect
+	github.com/spf13/cobra v1.1.1
 	golang.org/x/oauth2 v0.0.0-20200902213428-5d25da1a8d43 // indirect
 	golang.org/x/tools v0.0.0-20200904185747-39188db58858 // indirect
+	gopkg.in/yaml.v2 v2.3.0
 	k8s.io/api v0.17.9
 	k8s.io/apimachinery v0.17.9
 	k8s.io/client-go v0.17.9
@@ -22,4 +23,5 @@ require (
 	sigs.k8s.io/cluster-api v0.3.14
 	sigs.k8s.io/cluster-api-provider-aws v0.6.4
 	sigs.k8s.io/controller-runtime v0.5.14
+	sigs.k8s.io/yaml v1.2.0
 )
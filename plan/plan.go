@@ -0,0 +1,281 @@
+// Package plan describes everything a migration is going to do, before any
+// object is created or any DNS record is changed.
+package plan
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	capiawsv1alpha3 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+
+	"github.com/giantswarm/aws-gs-to-capi/capi"
+	"github.com/giantswarm/aws-gs-to-capi/state"
+)
+
+const (
+	ActionApply   = "apply"
+	ActionRoute53 = "route53"
+	ActionManual  = "manual"
+	ActionWait    = "wait"
+)
+
+type Config struct {
+	Crs *capi.Crs
+
+	// APIDomain is the domain of the API DNS record, i.e. the record is
+	// api.<APIDomain>.
+	APIDomain string
+	// CurrentDNSTarget is the current target of the API DNS record. It is
+	// printed as unknown when empty.
+	CurrentDNSTarget string
+	// SourceAPIVersion is the version of the GS infrastructure CRs the
+	// cluster was read from. It is left out when empty.
+	SourceAPIVersion string
+	// State is the state of the migration, nil when it has not been started.
+	// The steps of its completed phases are marked, since resume skips them.
+	State *state.Migration
+}
+
+type Step struct {
+	Phase       string
+	Action      string
+	Description string
+	// Completed is true if the phase of the step has been completed.
+	Completed bool
+}
+
+type Plan struct {
+	ClusterID string
+	Versions  []string
+	Network   []string
+	Steps     []Step
+	// Started is true if the migration has been started, i.e. the plan shows
+	// what resume is going to do.
+	Started bool
+}
+
+// New builds the ordered list of steps of a migration.
+func New(config Config) (*Plan, error) {
+	crs := config.Crs
+
+	p := &Plan{
+		ClusterID: crs.Cluster.Name,
+		Versions:  versions(crs.Versions, config.SourceAPIVersion, crs.APIVersion()),
+		Network:   network(crs),
+		Started:   config.State != nil,
+	}
+
+	err := p.addApplySteps(state.PhaseControlPlane, crs.ControlPlaneObjects())
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	controlPlane := fmt.Sprintf("KubeadmControlPlane %s/%s", crs.ControlPlane.Namespace, crs.ControlPlane.Name)
+	if crs.Topology != nil {
+		controlPlane = fmt.Sprintf("Cluster %s/%s topology", crs.Cluster.Namespace, crs.Cluster.Name)
+	}
+	p.Steps = append(p.Steps, Step{
+		Phase:       state.PhaseControlPlaneScale,
+		Action:      ActionWait,
+		Description: fmt.Sprintf("wait for the first control plane machine of %s to be ready", controlPlane),
+	})
+	for replicas := int32(2); replicas <= crs.ControlPlaneReplicas(); replicas++ {
+		p.Steps = append(p.Steps,
+			Step{
+				Phase:       state.PhaseControlPlaneScale,
+				Action:      ActionApply,
+				Description: fmt.Sprintf("scale %s to %d control plane replicas", controlPlane, replicas),
+			},
+			Step{
+				Phase:       state.PhaseControlPlaneScale,
+				Action:      ActionWait,
+				Description: fmt.Sprintf("wait for %d ready control plane machines", replicas),
+			},
+		)
+	}
+
+	awsCluster := fmt.Sprintf("AWSCluster %s/%s", crs.AWSCluster.Namespace, crs.AWSCluster.Name)
+	if crs.Topology != nil {
+		awsCluster = fmt.Sprintf("AWSCluster of Cluster %s/%s", crs.Cluster.Namespace, crs.Cluster.Name)
+	}
+
+	currentTarget := config.CurrentDNSTarget
+	if currentTarget == "" {
+		currentTarget = "<unknown>"
+	}
+	p.Steps = append(p.Steps,
+		Step{
+			Phase:       state.PhaseDNS,
+			Action:      ActionWait,
+			Description: fmt.Sprintf("wait for the API ELB of %s and healthy control plane instances", awsCluster),
+		},
+		Step{
+			Phase:       state.PhaseDNS,
+			Action:      ActionRoute53,
+			Description: fmt.Sprintf("UPSERT A api.%s: alias %s -> new API ELB", config.APIDomain, currentTarget),
+		},
+		Step{
+			Phase:       state.PhaseDNS,
+			Action:      ActionManual,
+			Description: "remove the kube-apiserver and kube-controller-manager manifests from the old masters",
+		},
+	)
+
+	err = p.addApplySteps(state.PhaseNodePools, crs.NodePoolObjects())
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	p.Steps = append(p.Steps, Step{
+		Phase:       state.PhaseNodePools,
+		Action:      ActionManual,
+		Description: "once the new nodes are ready, decommission the old masters and workers of the GS cluster",
+	})
+
+	if config.State != nil {
+		for i := range p.Steps {
+			p.Steps[i].Completed = config.State.Completed(p.Steps[i].Phase)
+		}
+	}
+
+	return p, nil
+}
+
+// Print writes the plan in a human readable form.
+func (p *Plan) Print(w io.Writer) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Migration plan for cluster %q\n\n", p.ClusterID)
+
+	fmt.Fprintf(&b, "Versions:\n")
+	for _, v := range p.Versions {
+		fmt.Fprintf(&b, "  %s\n", v)
+	}
+
+	fmt.Fprintf(&b, "\nDiscovered AWS resources:\n")
+	for _, n := range p.Network {
+		fmt.Fprintf(&b, "  %s\n", n)
+	}
+
+	phase := ""
+	next := ""
+	for i, s := range p.Steps {
+		if s.Phase != phase {
+			phase = s.Phase
+			if s.Completed {
+				fmt.Fprintf(&b, "\nPhase %q (completed, skipped by resume):\n", phase)
+			} else {
+				fmt.Fprintf(&b, "\nPhase %q:\n", phase)
+			}
+		}
+		if !s.Completed && next == "" {
+			next = s.Phase
+		}
+		fmt.Fprintf(&b, "  %2d. [%s] %s\n", i+1, s.Action, s.Description)
+	}
+
+	if p.Started {
+		if next == "" {
+			fmt.Fprintf(&b, "\nAll phases are completed, resume has nothing to do.\n")
+		} else {
+			fmt.Fprintf(&b, "\nResume continues with phase %q.\n", next)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (p *Plan) addApplySteps(phase string, objs []runtime.Object) error {
+	for _, o := range objs {
+		m, err := meta.Accessor(o)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		p.Steps = append(p.Steps, Step{
+			Phase:       phase,
+			Action:      ActionApply,
+			Description: fmt.Sprintf("%s %s/%s", o.GetObjectKind().GroupVersionKind().Kind, m.GetNamespace(), m.GetName()),
+		})
+	}
+
+	return nil
+}
+
+func versions(v capi.Versions, sourceAPIVersion string, capiAPIVersion string) []string {
+	var lines []string
+	if sourceAPIVersion != "" {
+		lines = append(lines, fmt.Sprintf("GS CRs infrastructure.giantswarm.io/%s", sourceAPIVersion))
+	}
+
+	return append(lines,
+		fmt.Sprintf("GS release %s (Kubernetes %s)", v.Release, v.SourceKubernetes),
+		fmt.Sprintf("Kubernetes %s", v.Kubernetes),
+		fmt.Sprintf("etcd %s, etcdctl %s", v.Etcd, v.Etcdctl),
+		fmt.Sprintf("CAPI objects %s", capiAPIVersion),
+	)
+}
+
+func network(crs *capi.Crs) []string {
+	vpc := crs.AWSCluster.Spec.NetworkSpec.VPC
+
+	var lines []string
+	lines = append(lines, fmt.Sprintf("VPC %s (%s)", vpc.ID, vpc.CidrBlock))
+	if vpc.InternetGatewayID != nil && *vpc.InternetGatewayID != "" {
+		lines = append(lines, fmt.Sprintf("internet gateway %s", *vpc.InternetGatewayID))
+	}
+	if n := crs.Cluster.Spec.ClusterNetwork; n != nil && n.Services != nil {
+		lines = append(lines, fmt.Sprintf("services %v, cluster domain %s", n.Services.CIDRBlocks, n.ServiceDomain))
+	}
+
+	for _, s := range crs.AWSCluster.Spec.NetworkSpec.Subnets {
+		t := "private"
+		if s.IsPublic {
+			t = "public"
+		}
+		lines = append(lines, fmt.Sprintf("subnet %s %s %s (%s)", s.ID, s.CidrBlock, s.AvailabilityZone, t))
+	}
+
+	lines = append(lines, fmt.Sprintf("control plane %d replicas across %s", crs.ControlPlaneReplicas(), strings.Join(crs.ControlPlaneAZs(), ", ")))
+	for _, sg := range crs.ControlPlaneMachineTemplate.Spec.Template.Spec.AdditionalSecurityGroups {
+		if sg.ID != nil {
+			lines = append(lines, fmt.Sprintf("control plane security group %s", *sg.ID))
+		}
+	}
+
+	for _, mp := range crs.MachinePools {
+		var sgs, subnets []capiawsv1alpha3.AWSResourceReference
+		if mp.Mode == capi.NodePoolModeMachineDeployment {
+			for _, t := range mp.AWSMachineTemplates {
+				// All templates of a node pool share the security group.
+				sgs = t.Spec.Template.Spec.AdditionalSecurityGroups
+				subnets = append(subnets, *t.Spec.Template.Spec.Subnet)
+			}
+		} else {
+			sgs = mp.AWSMachinePool.Spec.AWSLaunchTemplate.AdditionalSecurityGroups
+			subnets = mp.AWSMachinePool.Spec.Subnets
+		}
+
+		for _, sg := range sgs {
+			if sg.ID != nil {
+				lines = append(lines, fmt.Sprintf("node pool %s security group %s", mp.NodePoolID, *sg.ID))
+			}
+		}
+		for _, s := range subnets {
+			if s.ID != nil {
+				lines = append(lines, fmt.Sprintf("node pool %s subnet %s", mp.NodePoolID, *s.ID))
+			}
+		}
+	}
+
+	return lines
+}
@@ -0,0 +1,254 @@
+package plan
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	awsv1alpha2 "github.com/giantswarm/apiextensions/pkg/apis/infrastructure/v1alpha2"
+	releasev1alpha1 "github.com/giantswarm/apiextensions/pkg/apis/release/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/aws-gs-to-capi/capi"
+	"github.com/giantswarm/aws-gs-to-capi/giantswarm"
+	"github.com/giantswarm/aws-gs-to-capi/state"
+)
+
+func Test_New_Steps(t *testing.T) {
+	p, err := New(Config{
+		Crs:              newCrs(t),
+		APIDomain:        "abc12.k8s.example.com",
+		CurrentDNSTarget: "old-elb",
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	expected := []Step{
+		{Phase: state.PhaseControlPlane, Action: ActionApply, Description: "Secret org-acme/abc12-custom-files"},
+		{Phase: state.PhaseControlPlane, Action: ActionApply, Description: "Secret org-acme/abc12-etcd"},
+		{Phase: state.PhaseControlPlane, Action: ActionApply, Description: "Secret org-acme/abc12-sa"},
+		{Phase: state.PhaseControlPlane, Action: ActionApply, Description: "Secret org-acme/abc12-ca"},
+		{Phase: state.PhaseControlPlane, Action: ActionApply, Description: "Cluster org-acme/abc12"},
+		{Phase: state.PhaseControlPlane, Action: ActionApply, Description: "AWSCluster org-acme/abc12"},
+		{Phase: state.PhaseControlPlane, Action: ActionApply, Description: "KubeadmControlPlane org-acme/abc12-control-plane"},
+		{Phase: state.PhaseControlPlane, Action: ActionApply, Description: "AWSMachineTemplate org-acme/abc12-control-plane"},
+		{Phase: state.PhaseControlPlaneScale, Action: ActionWait, Description: "wait for the first control plane machine of KubeadmControlPlane org-acme/abc12-control-plane to be ready"},
+		{Phase: state.PhaseControlPlaneScale, Action: ActionApply, Description: "scale KubeadmControlPlane org-acme/abc12-control-plane to 2 control plane replicas"},
+		{Phase: state.PhaseControlPlaneScale, Action: ActionWait, Description: "wait for 2 ready control plane machines"},
+		{Phase: state.PhaseControlPlaneScale, Action: ActionApply, Description: "scale KubeadmControlPlane org-acme/abc12-control-plane to 3 control plane replicas"},
+		{Phase: state.PhaseControlPlaneScale, Action: ActionWait, Description: "wait for 3 ready control plane machines"},
+		{Phase: state.PhaseDNS, Action: ActionWait, Description: "wait for the API ELB of AWSCluster org-acme/abc12 and healthy control plane instances"},
+		{Phase: state.PhaseDNS, Action: ActionRoute53, Description: "UPSERT A api.abc12.k8s.example.com: alias old-elb -> new API ELB"},
+		{Phase: state.PhaseDNS, Action: ActionManual, Description: "remove the kube-apiserver and kube-controller-manager manifests from the old masters"},
+		{Phase: state.PhaseNodePools, Action: ActionApply, Description: "AWSMachinePool org-acme/abc12-worker-np001"},
+		{Phase: state.PhaseNodePools, Action: ActionApply, Description: "KubeadmConfig org-acme/abc12-worker-np001"},
+		{Phase: state.PhaseNodePools, Action: ActionApply, Description: "MachinePool org-acme/abc12-worker-np001"},
+		{Phase: state.PhaseNodePools, Action: ActionManual, Description: "once the new nodes are ready, decommission the old masters and workers of the GS cluster"},
+	}
+
+	if len(p.Steps) != len(expected) {
+		t.Fatalf("New() has %d steps, want %d", len(p.Steps), len(expected))
+	}
+	for i := range expected {
+		if p.Steps[i] != expected[i] {
+			t.Errorf("step %d = %+v, want %+v", i+1, p.Steps[i], expected[i])
+		}
+	}
+}
+
+func Test_Plan_Print_State(t *testing.T) {
+	testCases := []struct {
+		name            string
+		state           *state.Migration
+		expectedPhases  []string
+		expectedSummary string
+	}{
+		{
+			name:  "case 0: fresh migration",
+			state: nil,
+			expectedPhases: []string{
+				`Phase "control-plane":`,
+				`Phase "control-plane-scale":`,
+				`Phase "dns":`,
+				`Phase "node-pools":`,
+			},
+		},
+		{
+			name:  "case 1: started without completed phases",
+			state: newState(),
+			expectedPhases: []string{
+				`Phase "control-plane":`,
+				`Phase "control-plane-scale":`,
+				`Phase "dns":`,
+				`Phase "node-pools":`,
+			},
+			expectedSummary: `Resume continues with phase "control-plane".`,
+		},
+		{
+			name:  "case 2: after control-plane",
+			state: newState(state.PhaseControlPlane),
+			expectedPhases: []string{
+				`Phase "control-plane" (completed, skipped by resume):`,
+				`Phase "control-plane-scale":`,
+				`Phase "dns":`,
+				`Phase "node-pools":`,
+			},
+			expectedSummary: `Resume continues with phase "control-plane-scale".`,
+		},
+		{
+			name:  "case 3: after dns, with the control plane scaled",
+			state: newState(state.PhaseControlPlane, state.PhaseControlPlaneScale, state.PhaseDNS),
+			expectedPhases: []string{
+				`Phase "control-plane" (completed, skipped by resume):`,
+				`Phase "control-plane-scale" (completed, skipped by resume):`,
+				`Phase "dns" (completed, skipped by resume):`,
+				`Phase "node-pools":`,
+			},
+			expectedSummary: `Resume continues with phase "node-pools".`,
+		},
+		{
+			name:  "case 4: completed",
+			state: newState(state.Phases...),
+			expectedPhases: []string{
+				`Phase "control-plane" (completed, skipped by resume):`,
+				`Phase "control-plane-scale" (completed, skipped by resume):`,
+				`Phase "dns" (completed, skipped by resume):`,
+				`Phase "node-pools" (completed, skipped by resume):`,
+			},
+			expectedSummary: "All phases are completed, resume has nothing to do.",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := New(Config{
+				Crs:       newCrs(t),
+				APIDomain: "abc12.k8s.example.com",
+				State:     tc.state,
+			})
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			var b bytes.Buffer
+			err = p.Print(&b)
+			if err != nil {
+				t.Fatalf("Print() error = %v", err)
+			}
+
+			var phases []string
+			var summary string
+			lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+			for _, l := range lines {
+				if strings.HasPrefix(l, "Phase ") {
+					phases = append(phases, l)
+				}
+			}
+			if last := lines[len(lines)-1]; !strings.HasPrefix(last, "  ") {
+				summary = last
+			}
+
+			if !reflect.DeepEqual(phases, tc.expectedPhases) {
+				t.Errorf("Print() phases = %q, want %q", phases, tc.expectedPhases)
+			}
+			if summary != tc.expectedSummary {
+				t.Errorf("Print() summary = %q, want %q", summary, tc.expectedSummary)
+			}
+		})
+	}
+}
+
+func newCrs(t *testing.T) *capi.Crs {
+	t.Helper()
+
+	meta := func(name string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "org-acme",
+			Labels:    map[string]string{"giantswarm.io/cluster": "abc12"},
+		}
+	}
+	secret := func(name string, keys ...string) *v1.Secret {
+		s := &v1.Secret{ObjectMeta: meta(name), Data: map[string][]byte{}}
+		for _, k := range keys {
+			s.Data[k] = []byte(k)
+		}
+		return s
+	}
+
+	ac := &awsv1alpha2.AWSCluster{ObjectMeta: meta("abc12")}
+	ac.Spec.Cluster.DNS.Domain = "example.com"
+	ac.Spec.Provider.Region = "eu-west-1"
+	ac.Spec.Provider.Master.InstanceType = "m5.xlarge"
+	ac.Spec.Provider.Pods.CIDRBlock = "10.2.0.0/16"
+	ac.Status.Provider.Network.VPCID = "vpc-1"
+	ac.Status.Provider.Network.CIDR = "10.1.0.0/24"
+
+	cp := &awsv1alpha2.AWSControlPlane{ObjectMeta: meta("cp1")}
+	cp.Spec.InstanceType = "m5.xlarge"
+	cp.Spec.AvailabilityZones = []string{"eu-west-1a"}
+
+	g8s := &awsv1alpha2.G8sControlPlane{ObjectMeta: meta("cp1")}
+	g8s.Spec.Replicas = 3
+
+	md := &awsv1alpha2.AWSMachineDeployment{ObjectMeta: meta("np001")}
+	md.Spec.NodePool.Scaling.Min = 1
+	md.Spec.NodePool.Scaling.Max = 3
+	md.Spec.Provider.Worker.InstanceType = "m5.large"
+	md.Spec.Provider.AvailabilityZones = []string{"eu-west-1a"}
+
+	gsCrs := &giantswarm.GSClusterCrs{
+		Namespace:             "org-acme",
+		AWSCluster:            ac,
+		AWSControlPlane:       cp,
+		G8sControlPlane:       g8s,
+		AWSMachineDeployments: []*awsv1alpha2.AWSMachineDeployment{md},
+		Release: &releasev1alpha1.Release{
+			ObjectMeta: metav1.ObjectMeta{Name: "v14.1.0"},
+			Spec: releasev1alpha1.ReleaseSpec{
+				Components: []releasev1alpha1.ReleaseSpecComponent{
+					{Name: "kubernetes", Version: "1.19.9"},
+					{Name: "etcd", Version: "3.4.14"},
+				},
+			},
+		},
+		EtcdCerts:      secret("abc12-etcd1", "ca", "crt", "key"),
+		SACerts:        secret("abc12-service-account", "crt", "key"),
+		EncryptionKey:  secret("abc12-encryption", "encryption"),
+		KubeproxyCerts: secret("abc12-worker", "ca", "crt", "key"),
+		VaultCAKey:     "key",
+		Network: &giantswarm.Network{
+			MasterSecurityGroupID: "sg-master",
+			Subnets: []giantswarm.Subnet{
+				{ID: "subnet-a", CIDR: "10.1.0.0/26", AvailabilityZone: "eu-west-1a"},
+			},
+			NodePools: map[string]giantswarm.NodePoolNetwork{
+				"np001": {SecurityGroupID: "sg-np001", Subnets: []giantswarm.Subnet{{ID: "subnet-np", CIDR: "10.1.1.0/24", AvailabilityZone: "eu-west-1a"}}},
+			},
+		},
+		ClusterNetwork: &giantswarm.ClusterNetwork{ServiceCIDR: "172.31.0.0/16", ClusterDomain: "cluster.local"},
+	}
+
+	crs, err := capi.TransformGsToCAPICrs(gsCrs, capi.Config{})
+	if err != nil {
+		t.Fatalf("capi.TransformGsToCAPICrs() error = %v", err)
+	}
+
+	return crs
+}
+
+func newState(completed ...string) *state.Migration {
+	s := &state.Migration{
+		ClusterID: "abc12",
+		StartedAt: time.Now().UTC(),
+	}
+	for _, p := range completed {
+		_ = s.Complete(p, nil)
+	}
+
+	return s
+}
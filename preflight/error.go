@@ -0,0 +1,30 @@
+package preflight
+
+import "github.com/giantswarm/microerror"
+
+var notFoundError = &microerror.Error{
+	Kind: "notFoundError",
+}
+
+// IsNotFound asserts notFoundError.
+func IsNotFound(err error) bool {
+	return microerror.Cause(err) == notFoundError
+}
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var missingPolicyError = &microerror.Error{
+	Kind: "missingPolicyError",
+}
+
+// IsMissingPolicy asserts missingPolicyError.
+func IsMissingPolicy(err error) bool {
+	return microerror.Cause(err) == missingPolicyError
+}
@@ -0,0 +1,122 @@
+// Package preflight checks that the AWS resources referenced by the generated
+// CAPI objects exist, before any object is created.
+package preflight
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/giantswarm/microerror"
+)
+
+const (
+	awsErrorKeyPairNotFound = "InvalidKeyPair.NotFound"
+)
+
+type Config struct {
+	// Region is the AWS region of the cluster.
+	Region string
+}
+
+type Preflight struct {
+	region  string
+	session *session.Session
+}
+
+func New(config Config) (*Preflight, error) {
+	if config.Region == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Region must not be empty", config)
+	}
+
+	s, err := session.NewSession(&aws.Config{
+		Region: aws.String(config.Region),
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	p := &Preflight{
+		region:  config.Region,
+		session: s,
+	}
+
+	return p, nil
+}
+
+// CheckSSHKeyPair checks that the EC2 key pair exists in the region.
+func (p *Preflight) CheckSSHKeyPair(name string) error {
+	_, err := ec2.New(p.session).DescribeKeyPairs(&ec2.DescribeKeyPairsInput{
+		KeyNames: aws.StringSlice([]string{name}),
+	})
+	if isAWSError(err, awsErrorKeyPairNotFound) {
+		return microerror.Maskf(notFoundError, "EC2 key pair %q not found in region %s", name, p.region)
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// CheckInstanceProfile checks that the IAM instance profile exists and that
+// its role has all of the given policies, either attached or inline.
+func (p *Preflight) CheckInstanceProfile(name string, policies []string) error {
+	iamClient := iam.New(p.session)
+
+	o, err := iamClient.GetInstanceProfile(&iam.GetInstanceProfileInput{
+		InstanceProfileName: aws.String(name),
+	})
+	if isAWSError(err, iam.ErrCodeNoSuchEntityException) {
+		return microerror.Maskf(notFoundError, "IAM instance profile %q not found", name)
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+	if len(o.InstanceProfile.Roles) == 0 {
+		return microerror.Maskf(missingPolicyError, "IAM instance profile %q has no role", name)
+	}
+	role := o.InstanceProfile.Roles[0].RoleName
+
+	found := map[string]bool{}
+	err = iamClient.ListAttachedRolePoliciesPages(
+		&iam.ListAttachedRolePoliciesInput{RoleName: role},
+		func(page *iam.ListAttachedRolePoliciesOutput, lastPage bool) bool {
+			for _, p := range page.AttachedPolicies {
+				found[*p.PolicyName] = true
+			}
+			return true
+		},
+	)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	err = iamClient.ListRolePoliciesPages(
+		&iam.ListRolePoliciesInput{RoleName: role},
+		func(page *iam.ListRolePoliciesOutput, lastPage bool) bool {
+			for _, p := range page.PolicyNames {
+				found[*p] = true
+			}
+			return true
+		},
+	)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var missing []string
+	for _, policy := range policies {
+		if !found[policy] {
+			missing = append(missing, policy)
+		}
+	}
+	if len(missing) > 0 {
+		return microerror.Maskf(missingPolicyError, "role %q of IAM instance profile %q lacks the policies %v", *role, name, missing)
+	}
+
+	return nil
+}
+
+func isAWSError(err error, code string) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == code
+}
@@ -0,0 +1,187 @@
+package render
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/aws-gs-to-capi/capi"
+	"github.com/giantswarm/aws-gs-to-capi/sops"
+)
+
+const (
+	kustomizationFileName = "kustomization.yaml"
+	sopsConfigFileName    = ".sops.yaml"
+
+	controlPlaneDir = "control-plane"
+	nodePoolsDir    = "node-pools"
+	secretsDir      = "secrets"
+)
+
+type sopsConfig struct {
+	CreationRules []sopsCreationRule `json:"creation_rules"`
+}
+
+type sopsCreationRule struct {
+	PathRegex      string `json:"path_regex"`
+	EncryptedRegex string `json:"encrypted_regex"`
+	Age            string `json:"age"`
+}
+
+type kustomization struct {
+	APIVersion string   `json:"apiVersion"`
+	Kind       string   `json:"kind"`
+	Resources  []string `json:"resources"`
+}
+
+// WriteGitOps writes the objects as a kustomize directory tree which can be
+// reconciled by Flux. The tree is created in dir/<cluster ID>.
+//
+//	<cluster ID>/
+//	  kustomization.yaml
+//	  control-plane/
+//	  node-pools/<node pool ID>/
+//	  secrets/
+//
+// Every directory contains its own kustomization.yaml. The secrets are kept in
+// a separate directory so that they can be encrypted on their own. When
+// secrets are encrypted a .sops.yaml is added, so that sops re-encrypts edited
+// secrets for the same recipients.
+func (r *Renderer) WriteGitOps(dir string, crs *capi.Crs) error {
+	clusterDir := filepath.Join(dir, crs.Cluster.Name)
+
+	resources := []string{secretsDir, controlPlaneDir}
+
+	err := r.writeKustomizeDir(filepath.Join(clusterDir, secretsDir), crs, crs.SecretObjects())
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	// The node pools of a topology are part of the Cluster, so there are no
+	// node pool directories.
+	clusterObjs := crs.ClusterObjects()
+	machinePools := crs.MachinePools
+	if crs.Topology != nil {
+		clusterObjs = crs.NodePoolObjects()
+		machinePools = nil
+	}
+
+	err = r.writeKustomizeDir(filepath.Join(clusterDir, controlPlaneDir), crs, clusterObjs)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	for _, mp := range machinePools {
+		npDir := filepath.Join(nodePoolsDir, mp.NodePoolID)
+
+		err = r.writeKustomizeDir(filepath.Join(clusterDir, npDir), crs, mp.Objects())
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		resources = append(resources, npDir)
+	}
+
+	err = writeKustomization(clusterDir, resources)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if r.Encrypts() {
+		err = r.writeSopsConfig(clusterDir)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// writeKustomizeDir writes every object, converted into the CAPI API version
+// of crs, into its own file in dir together with a kustomization.yaml listing
+// all of them.
+func (r *Renderer) writeKustomizeDir(dir string, crs *capi.Crs, objs []runtime.Object) error {
+	err := mkdir(dir)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	objs, err = crs.Write(objs)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var resources []string
+	for _, o := range objs {
+		b, err := r.Marshal(o)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		name, err := FileName(o)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		err = WriteFile(filepath.Join(dir, name), b)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		resources = append(resources, name)
+	}
+
+	err = writeKustomization(dir, resources)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func writeKustomization(dir string, resources []string) error {
+	k := kustomization{
+		APIVersion: "kustomize.config.k8s.io/v1beta1",
+		Kind:       "Kustomization",
+		Resources:  resources,
+	}
+
+	b, err := yaml.Marshal(k)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = WriteFile(filepath.Join(dir, kustomizationFileName), b)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (r *Renderer) writeSopsConfig(dir string) error {
+	c := sopsConfig{
+		CreationRules: []sopsCreationRule{
+			{
+				PathRegex:      secretsDir + "/.*\\.yaml$",
+				EncryptedRegex: sops.EncryptedRegex,
+				Age:            strings.Join(r.ageRecipients, ","),
+			},
+		},
+	}
+
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = WriteFile(filepath.Join(dir, sopsConfigFileName), b)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
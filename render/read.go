@@ -0,0 +1,113 @@
+package render
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"filippo.io/age"
+	"github.com/giantswarm/microerror"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/aws-gs-to-capi/sops"
+)
+
+// ReadDir reads all objects rendered into dir, recursively and in file name
+// order. Both layouts as well as multi-document streams are supported. Secrets
+// encrypted in the SOPS format are decrypted with the given age identities.
+// Secrets are returned first so that they exist before the resources
+// referencing them.
+func ReadDir(dir string, identities []age.Identity) ([]runtime.Object, error) {
+	var paths []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		if info.IsDir() || !isManifest(info.Name()) {
+			return nil
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	sort.Strings(paths)
+
+	var objs []runtime.Object
+	for _, p := range paths {
+		o, err := readFile(p, identities)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		objs = append(objs, o...)
+	}
+
+	sort.SliceStable(objs, func(i, j int) bool {
+		return isSecret(objs[i]) && !isSecret(objs[j])
+	})
+
+	return objs, nil
+}
+
+func readFile(path string, identities []age.Identity) ([]runtime.Object, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var objs []runtime.Object
+	r := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(b)))
+	for {
+		doc, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+
+		if sops.IsEncrypted(doc) {
+			doc, err = sops.Decrypt(doc, identities)
+			if err != nil {
+				return nil, microerror.Maskf(invalidObjectError, "failed to decrypt %s: %s", path, err)
+			}
+		}
+
+		j, err := yaml.YAMLToJSON(doc)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		o := &unstructured.Unstructured{}
+		err = o.UnmarshalJSON(j)
+		if err != nil {
+			return nil, microerror.Maskf(invalidObjectError, "failed to decode %s: %s", path, err)
+		}
+		objs = append(objs, o)
+	}
+
+	return objs, nil
+}
+
+func isManifest(name string) bool {
+	if name == kustomizationFileName || name == sopsConfigFileName {
+		return false
+	}
+
+	ext := filepath.Ext(name)
+	return ext == ".yaml" || ext == ".yml"
+}
+
+func isSecret(o runtime.Object) bool {
+	return o.GetObjectKind().GroupVersionKind().Kind == secretKind
+}
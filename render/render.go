@@ -0,0 +1,161 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/aws-gs-to-capi/sops"
+)
+
+const (
+	documentSeparator = "---\n"
+
+	secretKind = "Secret"
+)
+
+type Config struct {
+	// AgeRecipients are the age public keys the data of Secret objects is
+	// encrypted for. Secrets are written in plain text when empty.
+	AgeRecipients []string
+}
+
+type Renderer struct {
+	ageRecipients []string
+}
+
+func New(config Config) (*Renderer, error) {
+	err := sops.ParseRecipients(config.AgeRecipients)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	r := &Renderer{
+		ageRecipients: config.AgeRecipients,
+	}
+
+	return r, nil
+}
+
+// Encrypts returns true if Secret objects are encrypted.
+func (r *Renderer) Encrypts() bool {
+	return len(r.ageRecipients) > 0
+}
+
+// Marshal serializes a single object to YAML. The data of Secret objects is
+// encrypted in the SOPS format if age recipients are configured.
+func (r *Renderer) Marshal(o runtime.Object) ([]byte, error) {
+	b, err := yaml.Marshal(o)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if r.Encrypts() && o.GetObjectKind().GroupVersionKind().Kind == secretKind {
+		b, err = sops.Encrypt(b, r.ageRecipients)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	return b, nil
+}
+
+// WriteStream writes all objects as one multi-document YAML stream. Encryption
+// is only supported for single documents, so the stream cannot be written
+// when age recipients are configured.
+func (r *Renderer) WriteStream(w io.Writer, objs []runtime.Object) error {
+	if r.Encrypts() {
+		return microerror.Maskf(invalidConfigError, "encrypted secrets can only be written to a directory")
+	}
+
+	for _, o := range objs {
+		b, err := r.Marshal(o)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		_, err = io.WriteString(w, documentSeparator)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		_, err = w.Write(b)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// WriteDir writes every object into its own file in dir. The files are
+// prefixed with the position of the object so that applying the directory
+// creates the objects in the right order.
+func (r *Renderer) WriteDir(dir string, objs []runtime.Object) error {
+	err := mkdir(dir)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	for i, o := range objs {
+		b, err := r.Marshal(o)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		name, err := FileName(o)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		err = WriteFile(filepath.Join(dir, fmt.Sprintf("%02d-%s", i, name)), b)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// FileName returns the file name used for the object, e.g.
+// "awscluster-a1b2c.yaml".
+func FileName(o runtime.Object) (string, error) {
+	m, err := meta.Accessor(o)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	kind := o.GetObjectKind().GroupVersionKind().Kind
+	if kind == "" {
+		return "", microerror.Maskf(invalidObjectError, "object %q has no kind", m.GetName())
+	}
+
+	return fmt.Sprintf("%s-%s.yaml", strings.ToLower(kind), m.GetName()), nil
+}
+
+// WriteFile writes the rendered content to path. The files may contain secrets
+// so they are only readable by the current user.
+func WriteFile(path string, b []byte) error {
+	err := ioutil.WriteFile(path, b, 0600)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func mkdir(dir string) error {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,273 @@
+package render
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/giantswarm/microerror"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/aws-gs-to-capi/capi"
+	"github.com/giantswarm/aws-gs-to-capi/giantswarm"
+)
+
+const (
+	valuesFileName = "values.yaml"
+)
+
+// DefaultValuesTemplate renders the values of a cluster-aws style chart.
+// Other charts or chart releases are supported by passing another template to
+// WriteValues, which gets the Values as data.
+const DefaultValuesTemplate = `# Values for the cluster-aws chart, generated from GS cluster {{ .ClusterID }}
+# (release {{ .Release }}).
+clusterName: {{ quote .ClusterID }}
+namespace: {{ quote .Namespace }}
+kubernetesVersion: {{ quote .KubernetesVersion }}
+
+aws:
+  region: {{ quote .Region }}
+
+network:
+  vpcId: {{ quote .Network.VPCID }}
+  vpcCIDR: {{ quote .Network.VPCCIDR }}
+{{- if .Network.InternetGatewayID }}
+  internetGatewayId: {{ quote .Network.InternetGatewayID }}
+{{- end }}
+  podCIDR: {{ quote .Network.PodCIDR }}
+  serviceCIDR: {{ quote .Network.ServiceCIDR }}
+  serviceDomain: {{ quote .Network.ServiceDomain }}
+  subnets:
+{{- range .Network.Subnets }}
+  - id: {{ quote .ID }}
+    cidrBlock: {{ quote .CIDR }}
+    availabilityZone: {{ quote .AvailabilityZone }}
+    isPublic: {{ .IsPublic }}
+{{- end }}
+
+controlPlane:
+  instanceType: {{ quote .ControlPlane.InstanceType }}
+  instanceProfile: {{ quote .ControlPlane.InstanceProfile }}
+  replicas: {{ .ControlPlane.Replicas }}
+  availabilityZones:
+{{- range .ControlPlane.AvailabilityZones }}
+  - {{ quote . }}
+{{- end }}
+  securityGroupId: {{ quote .ControlPlane.SecurityGroupID }}
+  etcdVersion: {{ quote .ControlPlane.EtcdVersion }}
+
+machinePools:
+{{- range .NodePools }}
+  {{ .ID }}:
+    instanceType: {{ quote .InstanceType }}
+    instanceProfile: {{ quote .InstanceProfile }}
+    minSize: {{ .MinSize }}
+    maxSize: {{ .MaxSize }}
+    securityGroupId: {{ quote .SecurityGroupID }}
+    subnets:
+{{- range .Subnets }}
+    - id: {{ quote .ID }}
+      availabilityZone: {{ quote .AvailabilityZone }}
+{{- end }}
+{{- end }}
+
+secrets:
+  customFiles: {{ quote .Secrets.CustomFiles }}
+  etcd: {{ quote .Secrets.Etcd }}
+  serviceAccount: {{ quote .Secrets.ServiceAccount }}
+  ca: {{ quote .Secrets.CA }}
+`
+
+// Values is the data of a values template. It holds everything a cluster
+// chart needs to recreate the GS cluster, secrets are only referenced by
+// name.
+type Values struct {
+	ClusterID         string
+	Namespace         string
+	Release           string
+	KubernetesVersion string
+	Region            string
+
+	Network      ValuesNetwork
+	ControlPlane ValuesControlPlane
+	NodePools    []ValuesNodePool
+	Secrets      ValuesSecrets
+}
+
+type ValuesNetwork struct {
+	VPCID             string
+	VPCCIDR           string
+	InternetGatewayID string
+	PodCIDR           string
+	ServiceCIDR       string
+	ServiceDomain     string
+	Subnets           []giantswarm.Subnet
+}
+
+type ValuesControlPlane struct {
+	InstanceType      string
+	InstanceProfile   string
+	Replicas          int
+	AvailabilityZones []string
+	SecurityGroupID   string
+	EtcdVersion       string
+}
+
+type ValuesNodePool struct {
+	ID              string
+	InstanceType    string
+	InstanceProfile string
+	MinSize         int
+	MaxSize         int
+	SecurityGroupID string
+	Subnets         []giantswarm.Subnet
+}
+
+// ValuesSecrets are the names of the secrets created next to the chart.
+type ValuesSecrets struct {
+	CustomFiles    string
+	Etcd           string
+	ServiceAccount string
+	CA             string
+}
+
+// NewValues collects the values of the GS cluster from the fetched GS CRs and
+// the CAPI CRs transformed from them.
+func NewValues(gsCrs *giantswarm.GSClusterCrs, crs *capi.Crs) *Values {
+	v := &Values{
+		ClusterID:         crs.Cluster.Name,
+		Namespace:         crs.Cluster.Namespace,
+		Release:           crs.Versions.Release,
+		KubernetesVersion: crs.Versions.Kubernetes,
+		Region:            crs.AWSCluster.Spec.Region,
+
+		Network: ValuesNetwork{
+			VPCID:             crs.AWSCluster.Spec.NetworkSpec.VPC.ID,
+			VPCCIDR:           crs.AWSCluster.Spec.NetworkSpec.VPC.CidrBlock,
+			InternetGatewayID: gsCrs.Network.InternetGatewayID,
+			Subnets:           gsCrs.Network.Subnets,
+		},
+		ControlPlane: ValuesControlPlane{
+			InstanceType:      crs.ControlPlaneMachineTemplate.Spec.Template.Spec.InstanceType,
+			InstanceProfile:   crs.ControlPlaneInstanceProfile(),
+			Replicas:          int(crs.ControlPlaneReplicas()),
+			AvailabilityZones: crs.ControlPlaneAZs(),
+			SecurityGroupID:   gsCrs.Network.MasterSecurityGroupID,
+			EtcdVersion:       crs.Versions.Etcd,
+		},
+		Secrets: ValuesSecrets{
+			CustomFiles:    crs.CustomFiles.Name,
+			Etcd:           crs.EtcdCerts.Name,
+			ServiceAccount: crs.SACerts.Name,
+			CA:             crs.CACerts.Name,
+		},
+	}
+
+	if n := crs.Cluster.Spec.ClusterNetwork; n != nil {
+		if n.Pods != nil && len(n.Pods.CIDRBlocks) > 0 {
+			v.Network.PodCIDR = n.Pods.CIDRBlocks[0]
+		}
+		if n.Services != nil && len(n.Services.CIDRBlocks) > 0 {
+			v.Network.ServiceCIDR = n.Services.CIDRBlocks[0]
+		}
+		v.Network.ServiceDomain = n.ServiceDomain
+	}
+
+	instanceProfiles := map[string]string{}
+	for _, mp := range crs.MachinePools {
+		instanceProfiles[mp.NodePoolID] = mp.InstanceProfile()
+	}
+
+	for _, md := range gsCrs.AWSMachineDeployments {
+		network := gsCrs.Network.NodePools[md.Name]
+		v.NodePools = append(v.NodePools, ValuesNodePool{
+			ID:              md.Name,
+			InstanceType:    md.Spec.Provider.Worker.InstanceType,
+			InstanceProfile: instanceProfiles[md.Name],
+			MinSize:         md.Spec.NodePool.Scaling.Min,
+			MaxSize:         md.Spec.NodePool.Scaling.Max,
+			SecurityGroupID: network.SecurityGroupID,
+			Subnets:         network.Subnets,
+		})
+	}
+
+	return v
+}
+
+// ReadValuesTemplate reads a values template from path. An empty path returns
+// DefaultValuesTemplate.
+func ReadValuesTemplate(path string) (string, error) {
+	if path == "" {
+		return DefaultValuesTemplate, nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return string(b), nil
+}
+
+// WriteValues renders the values with the given template and writes them to
+// w. The rendered values have to be valid YAML.
+func (r *Renderer) WriteValues(w io.Writer, tmpl string, values *Values) error {
+	funcs := template.FuncMap{
+		"quote": strconv.Quote,
+		"join":  strings.Join,
+	}
+
+	t, err := template.New("values").Funcs(funcs).Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return microerror.Maskf(invalidConfigError, "invalid values template: %s", err)
+	}
+
+	var b bytes.Buffer
+	err = t.Execute(&b, values)
+	if err != nil {
+		return microerror.Maskf(invalidConfigError, "failed to render values template: %s", err)
+	}
+
+	_, err = yaml.YAMLToJSON(b.Bytes())
+	if err != nil {
+		return microerror.Maskf(invalidConfigError, "values template does not render valid YAML: %s", err)
+	}
+
+	_, err = w.Write(b.Bytes())
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// WriteValuesDir writes the values to <dir>/values.yaml and the secrets they
+// reference to <dir>/secrets, which have to be created next to the chart.
+func (r *Renderer) WriteValuesDir(dir string, tmpl string, values *Values, crs *capi.Crs) error {
+	err := mkdir(dir)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var b bytes.Buffer
+	err = r.WriteValues(&b, tmpl, values)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = WriteFile(filepath.Join(dir, valuesFileName), b.Bytes())
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.writeKustomizeDir(filepath.Join(dir, secretsDir), crs, crs.SecretObjects())
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
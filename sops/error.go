@@ -0,0 +1,30 @@
+package sops
+
+import "github.com/giantswarm/microerror"
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var invalidDocumentError = &microerror.Error{
+	Kind: "invalidDocumentError",
+}
+
+// IsInvalidDocument asserts invalidDocumentError.
+func IsInvalidDocument(err error) bool {
+	return microerror.Cause(err) == invalidDocumentError
+}
+
+var notEncryptedError = &microerror.Error{
+	Kind: "notEncryptedError",
+}
+
+// IsNotEncrypted asserts notEncryptedError.
+func IsNotEncrypted(err error) bool {
+	return microerror.Cause(err) == notEncryptedError
+}
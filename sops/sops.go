@@ -0,0 +1,463 @@
+// Package sops encrypts and decrypts YAML documents in the format used by
+// Mozilla SOPS with age recipients. Only values below keys matching
+// EncryptedRegex are encrypted, e.g. the data of Kubernetes Secrets, so the
+// rest of the document stays readable.
+//
+// The output can be decrypted with the sops binary and by the Flux
+// kustomize-controller, files encrypted by sops with age recipients can be
+// decrypted by this package.
+package sops
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/giantswarm/microerror"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// EncryptedRegex matches the keys whose values are encrypted.
+	EncryptedRegex = "^(data|stringData)$"
+
+	metadataKey = "sops"
+	version     = "3.7.1"
+
+	dataKeySize = 32
+	nonceSize   = 32
+	tagSize     = 16
+)
+
+var (
+	encryptedRegex = regexp.MustCompile(EncryptedRegex)
+	encryptedValue = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.*),iv:(.+),tag:(.+),type:(.+)\]$`)
+)
+
+type ageKey struct {
+	Recipient string `yaml:"recipient"`
+	Enc       string `yaml:"enc"`
+}
+
+type metadata struct {
+	Age            []ageKey `yaml:"age"`
+	LastModified   string   `yaml:"lastmodified"`
+	MAC            string   `yaml:"mac"`
+	EncryptedRegex string   `yaml:"encrypted_regex"`
+	Version        string   `yaml:"version"`
+}
+
+// IsEncrypted returns true if the YAML document carries SOPS metadata.
+func IsEncrypted(doc []byte) bool {
+	var m map[string]interface{}
+	err := yaml.Unmarshal(doc, &m)
+	if err != nil {
+		return false
+	}
+
+	_, ok := m[metadataKey]
+	return ok
+}
+
+// Encrypt encrypts the values of all keys matching EncryptedRegex in the YAML
+// document with a new data key, which is encrypted for every age recipient.
+func Encrypt(doc []byte, recipients []string) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "at least one age recipient must be given")
+	}
+
+	var tree yaml.MapSlice
+	err := yaml.Unmarshal(doc, &tree)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	dataKey := make([]byte, dataKeySize)
+	_, err = rand.Read(dataKey)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	m := metadata{
+		LastModified:   time.Now().UTC().Format(time.RFC3339),
+		EncryptedRegex: EncryptedRegex,
+		Version:        version,
+	}
+
+	for _, r := range recipients {
+		enc, err := encryptDataKey(dataKey, r)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		m.Age = append(m.Age, ageKey{Recipient: r, Enc: enc})
+	}
+
+	h := sha512.New()
+	tree, err = walkBranch(encryptedRegex, tree, nil, false, func(v interface{}, path []string, encrypt bool) (interface{}, error) {
+		b, err := toBytes(v)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		_, _ = h.Write(b)
+
+		if !encrypt {
+			return v, nil
+		}
+		return encryptValue(v, dataKey, pathString(path))
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	m.MAC, err = encryptValue(macString(h), dataKey, m.LastModified)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	tree = append(tree, yaml.MapItem{Key: metadataKey, Value: m})
+
+	out, err := yaml.Marshal(tree)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return out, nil
+}
+
+// Decrypt decrypts a YAML document encrypted by Encrypt or by sops with age
+// recipients. The MAC of the document is verified and the SOPS metadata is
+// removed from the result.
+func Decrypt(doc []byte, identities []age.Identity) ([]byte, error) {
+	var tree yaml.MapSlice
+	err := yaml.Unmarshal(doc, &tree)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var m metadata
+	{
+		var found bool
+		var rest yaml.MapSlice
+		for _, item := range tree {
+			if item.Key != metadataKey {
+				rest = append(rest, item)
+				continue
+			}
+
+			b, err := yaml.Marshal(item.Value)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			err = yaml.Unmarshal(b, &m)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			found = true
+		}
+		if !found {
+			return nil, microerror.Maskf(notEncryptedError, "document has no %q metadata", metadataKey)
+		}
+		tree = rest
+	}
+
+	dataKey, err := decryptDataKey(m.Age, identities)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	regex := encryptedRegex
+	if m.EncryptedRegex != "" {
+		regex, err = regexp.Compile(m.EncryptedRegex)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	h := sha512.New()
+	tree, err = walkBranch(regex, tree, nil, false, func(v interface{}, path []string, encrypted bool) (interface{}, error) {
+		if encrypted {
+			s, ok := v.(string)
+			if !ok {
+				return nil, microerror.Maskf(invalidDocumentError, "value of %q is not encrypted", pathString(path))
+			}
+			v, err = decryptValue(s, dataKey, pathString(path))
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		}
+
+		b, err := toBytes(v)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		_, _ = h.Write(b)
+
+		return v, nil
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	mac, err := decryptValue(m.MAC, dataKey, m.LastModified)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if mac != macString(h) {
+		return nil, microerror.Maskf(invalidDocumentError, "MAC mismatch, the document has been modified")
+	}
+
+	out, err := yaml.Marshal(tree)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return out, nil
+}
+
+// ParseRecipients validates the given age recipients.
+func ParseRecipients(recipients []string) error {
+	for _, r := range recipients {
+		_, err := age.ParseX25519Recipient(r)
+		if err != nil {
+			return microerror.Maskf(invalidConfigError, "invalid age recipient %q: %s", r, err)
+		}
+	}
+
+	return nil
+}
+
+// ReadIdentities reads age identities from the given key file, as created by
+// age-keygen.
+func ReadIdentities(path string) ([]age.Identity, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	ids, err := age.ParseIdentities(bytes.NewReader(b))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return ids, nil
+}
+
+type leafFunc func(v interface{}, path []string, encrypt bool) (interface{}, error)
+
+// walkBranch calls f for every leaf of the tree in document order, the
+// same way sops does to compute the MAC. Values below a key matching regex
+// are marked for encryption. Nil values are skipped.
+func walkBranch(regex *regexp.Regexp, branch yaml.MapSlice, path []string, encrypt bool, f leafFunc) (yaml.MapSlice, error) {
+	for i, item := range branch {
+		key := fmt.Sprintf("%v", item.Key)
+		p := append(append([]string{}, path...), key)
+
+		v, err := walkValue(regex, item.Value, p, encrypt || regex.MatchString(key), f)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		branch[i].Value = v
+	}
+
+	return branch, nil
+}
+
+func walkValue(regex *regexp.Regexp, v interface{}, path []string, encrypt bool, f leafFunc) (interface{}, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case yaml.MapSlice:
+		return walkBranch(regex, v, path, encrypt, f)
+	case []interface{}:
+		for i, e := range v {
+			w, err := walkValue(regex, e, path, encrypt, f)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			v[i] = w
+		}
+		return v, nil
+	default:
+		return f(v, path, encrypt)
+	}
+}
+
+func encryptDataKey(dataKey []byte, recipient string) (string, error) {
+	r, err := age.ParseX25519Recipient(recipient)
+	if err != nil {
+		return "", microerror.Maskf(invalidConfigError, "invalid age recipient %q: %s", recipient, err)
+	}
+
+	var buf bytes.Buffer
+	aw := armor.NewWriter(&buf)
+	w, err := age.Encrypt(aw, r)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	_, err = w.Write(dataKey)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	err = w.Close()
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	err = aw.Close()
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return buf.String(), nil
+}
+
+func decryptDataKey(keys []ageKey, identities []age.Identity) ([]byte, error) {
+	if len(identities) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "at least one age identity must be given")
+	}
+
+	for _, k := range keys {
+		r, err := age.Decrypt(armor.NewReader(strings.NewReader(k.Enc)), identities...)
+		if err != nil {
+			continue
+		}
+		dataKey, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		return dataKey, nil
+	}
+
+	return nil, microerror.Maskf(invalidConfigError, "none of the given age identities matches the recipients of the document")
+}
+
+func encryptValue(v interface{}, dataKey []byte, additionalData string) (string, error) {
+	var plaintext []byte
+	var valueType string
+	switch v := v.(type) {
+	case string:
+		plaintext, valueType = []byte(v), "str"
+	case int:
+		plaintext, valueType = []byte(strconv.Itoa(v)), "int"
+	case float64:
+		plaintext, valueType = []byte(strconv.FormatFloat(v, 'f', -1, 64)), "float"
+	case bool:
+		plaintext, valueType = []byte(strconv.FormatBool(v)), "bool"
+	default:
+		return "", microerror.Maskf(invalidDocumentError, "cannot encrypt value of type %T", v)
+	}
+
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	nonce := make([]byte, nonceSize)
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	out := gcm.Seal(nil, nonce, plaintext, []byte(additionalData))
+
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:%s]",
+		base64.StdEncoding.EncodeToString(out[:len(out)-tagSize]),
+		base64.StdEncoding.EncodeToString(nonce),
+		base64.StdEncoding.EncodeToString(out[len(out)-tagSize:]),
+		valueType,
+	), nil
+}
+
+func decryptValue(s string, dataKey []byte, additionalData string) (interface{}, error) {
+	matches := encryptedValue.FindStringSubmatch(s)
+	if matches == nil {
+		return nil, microerror.Maskf(invalidDocumentError, "value is not in the sops format")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(matches[1])
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(matches[2])
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	tag, err := base64.StdEncoding.DecodeString(matches[3])
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	plaintext, err := gcm.Open(nil, nonce, append(data, tag...), []byte(additionalData))
+	if err != nil {
+		return nil, microerror.Maskf(invalidDocumentError, "failed to decrypt value: %s", err)
+	}
+
+	switch matches[4] {
+	case "str":
+		return string(plaintext), nil
+	case "int":
+		return strconv.Atoi(string(plaintext))
+	case "float":
+		return strconv.ParseFloat(string(plaintext), 64)
+	case "bool":
+		return strconv.ParseBool(string(plaintext))
+	default:
+		return nil, microerror.Maskf(invalidDocumentError, "unknown value type %q", matches[4])
+	}
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	gcm, err := cipher.NewGCMWithNonceSize(block, nonceSize)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return gcm, nil
+}
+
+// toBytes converts a leaf into the representation sops uses for the MAC.
+func toBytes(v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case string:
+		return []byte(v), nil
+	case int:
+		return []byte(strconv.Itoa(v)), nil
+	case float64:
+		return []byte(strconv.FormatFloat(v, 'f', -1, 64)), nil
+	case bool:
+		return []byte(strings.Title(strconv.FormatBool(v))), nil
+	default:
+		return nil, microerror.Maskf(invalidDocumentError, "cannot convert value of type %T", v)
+	}
+}
+
+func macString(h hash.Hash) string {
+	return fmt.Sprintf("%X", h.Sum(nil))
+}
+
+// pathString is the additional data sops authenticates every value with.
+func pathString(path []string) string {
+	return strings.Join(path, ":") + ":"
+}
@@ -0,0 +1,426 @@
+package sops
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"gopkg.in/yaml.v2"
+)
+
+const secret = `apiVersion: v1
+kind: Secret
+metadata:
+  name: abc12-etcd
+  namespace: org-acme
+  labels:
+    data: encrypted
+type: Opaque
+data:
+  ca: Y2E=
+  key: a2V5
+stringData:
+  replicas: 3
+  enabled: true
+  nested:
+    list:
+    - a
+    - b
+`
+
+func Test_Encrypt_RoundTrip(t *testing.T) {
+	id := newIdentity(t)
+
+	encrypted, err := Encrypt([]byte(secret), []string{id.Recipient().String()})
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if !IsEncrypted(encrypted) {
+		t.Fatalf("IsEncrypted() = false, want true")
+	}
+
+	decrypted, err := Decrypt(encrypted, []age.Identity{id})
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+
+	if got, want := normalize(t, decrypted), normalize(t, []byte(secret)); got != want {
+		t.Fatalf("Decrypt() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// Test_Encrypt_OnlyDataAndStringData checks that only the values below data
+// and stringData are encrypted. Like sops the regex matches any key of the
+// path, so a label named data is encrypted as well.
+func Test_Encrypt_OnlyDataAndStringData(t *testing.T) {
+	id := newIdentity(t)
+
+	encrypted, err := Encrypt([]byte(secret), []string{id.Recipient().String()})
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	leaves := map[string]interface{}{}
+	collectLeaves(unmarshal(t, encrypted), nil, leaves)
+
+	plain := map[string]interface{}{
+		"apiVersion":         "v1",
+		"kind":               "Secret",
+		"metadata:name":      "abc12-etcd",
+		"metadata:namespace": "org-acme",
+		"type":               "Opaque",
+	}
+	for path, want := range plain {
+		if got := leaves[path]; got != want {
+			t.Errorf("value of %s = %v, want %v", path, got, want)
+		}
+	}
+
+	for _, path := range []string{"metadata:labels:data", "data:ca", "data:key", "stringData:replicas", "stringData:enabled", "stringData:nested:list"} {
+		s, ok := leaves[path].(string)
+		if !ok || !strings.HasPrefix(s, "ENC[AES256_GCM,") {
+			t.Errorf("value of %s = %v, want it encrypted", path, leaves[path])
+		}
+	}
+}
+
+// Test_Encrypt_Format decrypts the output following the sops format with the
+// primitives used by sops instead of the functions of this package: a data
+// key encrypted with armored age, AES256-GCM with a 32 byte IV and the path
+// of the value as additional data, and a MAC over all values in document
+// order encrypted with the last modification time as additional data.
+func Test_Encrypt_Format(t *testing.T) {
+	id := newIdentity(t)
+
+	encrypted, err := Encrypt([]byte(secret), []string{id.Recipient().String()})
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	var doc struct {
+		Sops struct {
+			Age []struct {
+				Recipient string `yaml:"recipient"`
+				Enc       string `yaml:"enc"`
+			} `yaml:"age"`
+			LastModified   string `yaml:"lastmodified"`
+			MAC            string `yaml:"mac"`
+			EncryptedRegex string `yaml:"encrypted_regex"`
+			Version        string `yaml:"version"`
+		} `yaml:"sops"`
+		Metadata struct {
+			Labels map[string]string `yaml:"labels"`
+		} `yaml:"metadata"`
+		Data       map[string]string `yaml:"data"`
+		StringData struct {
+			Replicas string `yaml:"replicas"`
+			Enabled  string `yaml:"enabled"`
+			Nested   struct {
+				List []string `yaml:"list"`
+			} `yaml:"nested"`
+		} `yaml:"stringData"`
+	}
+	err = yaml.Unmarshal(encrypted, &doc)
+	if err != nil {
+		t.Fatalf("yaml.Unmarshal() error = %v", err)
+	}
+
+	if doc.Sops.EncryptedRegex != "^(data|stringData)$" {
+		t.Errorf("encrypted_regex = %q", doc.Sops.EncryptedRegex)
+	}
+	if len(doc.Sops.Age) != 1 || doc.Sops.Age[0].Recipient != id.Recipient().String() {
+		t.Fatalf("age = %v, want the recipient %s", doc.Sops.Age, id.Recipient())
+	}
+
+	r, err := age.Decrypt(armor.NewReader(strings.NewReader(doc.Sops.Age[0].Enc)), id)
+	if err != nil {
+		t.Fatalf("age.Decrypt() error = %v", err)
+	}
+	dataKey, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ioutil.ReadAll() error = %v", err)
+	}
+	if len(dataKey) != 32 {
+		t.Fatalf("data key has %d bytes, want 32", len(dataKey))
+	}
+
+	values := []struct {
+		encrypted string
+		path      string
+		plaintext string
+		valueType string
+	}{
+		{encrypted: doc.Metadata.Labels["data"], path: "metadata:labels:data:", plaintext: "encrypted", valueType: "str"},
+		{encrypted: doc.Data["ca"], path: "data:ca:", plaintext: "Y2E=", valueType: "str"},
+		{encrypted: doc.Data["key"], path: "data:key:", plaintext: "a2V5", valueType: "str"},
+		{encrypted: doc.StringData.Replicas, path: "stringData:replicas:", plaintext: "3", valueType: "int"},
+		{encrypted: doc.StringData.Enabled, path: "stringData:enabled:", plaintext: "true", valueType: "bool"},
+		{encrypted: doc.StringData.Nested.List[0], path: "stringData:nested:list:", plaintext: "a", valueType: "str"},
+		{encrypted: doc.StringData.Nested.List[1], path: "stringData:nested:list:", plaintext: "b", valueType: "str"},
+	}
+	for _, v := range values {
+		plaintext, valueType := openValue(t, v.encrypted, dataKey, v.path)
+		if plaintext != v.plaintext || valueType != v.valueType {
+			t.Errorf("value of %s = %q of type %s, want %q of type %s", v.path, plaintext, valueType, v.plaintext, v.valueType)
+		}
+	}
+
+	// All values in document order, booleans as sops writes them.
+	h := sha512.New()
+	for _, v := range []string{"v1", "Secret", "abc12-etcd", "org-acme", "encrypted", "Opaque", "Y2E=", "a2V5", "3", "True", "a", "b"} {
+		_, _ = h.Write([]byte(v))
+	}
+	mac, valueType := openValue(t, doc.Sops.MAC, dataKey, doc.Sops.LastModified)
+	if want := fmt.Sprintf("%X", h.Sum(nil)); mac != want || valueType != "str" {
+		t.Errorf("MAC = %q of type %s, want %q of type str", mac, valueType, want)
+	}
+}
+
+func Test_Decrypt_Errors(t *testing.T) {
+	id := newIdentity(t)
+	other := newIdentity(t)
+
+	encrypted, err := Encrypt([]byte(secret), []string{id.Recipient().String()})
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	testCases := []struct {
+		name       string
+		doc        []byte
+		identities []age.Identity
+		match      func(error) bool
+	}{
+		{
+			name:       "case 0: not encrypted",
+			doc:        []byte(secret),
+			identities: []age.Identity{id},
+			match:      IsNotEncrypted,
+		},
+		{
+			name:       "case 1: unknown identity",
+			doc:        encrypted,
+			identities: []age.Identity{other},
+			match:      IsInvalidConfig,
+		},
+		{
+			name:       "case 2: modified plain value",
+			doc:        []byte(strings.Replace(string(encrypted), "org-acme", "org-other", 1)),
+			identities: []age.Identity{id},
+			match:      IsInvalidDocument,
+		},
+		{
+			name:       "case 3: moved encrypted value",
+			doc:        swapData(t, encrypted),
+			identities: []age.Identity{id},
+			match:      IsInvalidDocument,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Decrypt(tc.doc, tc.identities)
+			if !tc.match(err) {
+				t.Fatalf("Decrypt() error = %v, want a matching error", err)
+			}
+		})
+	}
+}
+
+// Test_Decrypt_Sops decrypts testdata/secret.sops.yaml, which was encrypted
+// from testdata/secret.yaml by the encryption code of sops 3.7.3 for the age
+// identity in testdata/age.txt.
+func Test_Decrypt_Sops(t *testing.T) {
+	identities, err := ReadIdentities(filepath.Join("testdata", "age.txt"))
+	if err != nil {
+		t.Fatalf("ReadIdentities() error = %v", err)
+	}
+	encrypted := readFile(t, "secret.sops.yaml")
+
+	decrypted, err := Decrypt(encrypted, identities)
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+
+	if got, want := normalize(t, decrypted), normalize(t, readFile(t, "secret.yaml")); got != want {
+		t.Fatalf("Decrypt() =\n%s\nwant\n%s", got, want)
+	}
+
+	_, err = Decrypt([]byte(strings.Replace(string(encrypted), "org-acme", "org-other", 1)), identities)
+	if !IsInvalidDocument(err) {
+		t.Fatalf("Decrypt() of a modified document error = %v, want a matching error", err)
+	}
+}
+
+// Test_Encrypt_SopsDecrypt decrypts the output of Encrypt with the sops
+// binary. It is skipped when sops is not installed.
+func Test_Encrypt_SopsDecrypt(t *testing.T) {
+	bin, err := exec.LookPath("sops")
+	if err != nil {
+		t.Skip("sops binary not found in PATH")
+	}
+
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "age.txt")
+	encryptedFile := filepath.Join(dir, "secret.sops.yaml")
+
+	id := newIdentity(t)
+	err = ioutil.WriteFile(keyFile, []byte(id.String()+"\n"), 0600)
+	if err != nil {
+		t.Fatalf("ioutil.WriteFile() error = %v", err)
+	}
+
+	encrypted, err := Encrypt([]byte(secret), []string{id.Recipient().String()})
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	err = ioutil.WriteFile(encryptedFile, encrypted, 0600)
+	if err != nil {
+		t.Fatalf("ioutil.WriteFile() error = %v", err)
+	}
+
+	cmd := exec.Command(bin, "--decrypt", "--input-type", "yaml", "--output-type", "yaml", encryptedFile)
+	cmd.Env = append(os.Environ(), "SOPS_AGE_KEY_FILE="+keyFile)
+	decrypted, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("sops --decrypt error = %v", err)
+	}
+
+	if got, want := normalize(t, decrypted), normalize(t, []byte(secret)); got != want {
+		t.Fatalf("sops --decrypt =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func newIdentity(t *testing.T) *age.X25519Identity {
+	t.Helper()
+
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("age.GenerateX25519Identity() error = %v", err)
+	}
+
+	return id
+}
+
+func unmarshal(t *testing.T, doc []byte) yaml.MapSlice {
+	t.Helper()
+
+	var tree yaml.MapSlice
+	err := yaml.Unmarshal(doc, &tree)
+	if err != nil {
+		t.Fatalf("yaml.Unmarshal() error = %v", err)
+	}
+
+	return tree
+}
+
+func normalize(t *testing.T, doc []byte) string {
+	t.Helper()
+
+	b, err := yaml.Marshal(unmarshal(t, doc))
+	if err != nil {
+		t.Fatalf("yaml.Marshal() error = %v", err)
+	}
+
+	return string(b)
+}
+
+func readFile(t *testing.T, name string) []byte {
+	t.Helper()
+
+	b, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile() error = %v", err)
+	}
+
+	return b
+}
+
+// collectLeaves maps the paths of the leaves of tree to their values, lists
+// are kept as a whole.
+func collectLeaves(tree yaml.MapSlice, path []string, leaves map[string]interface{}) {
+	for _, item := range tree {
+		p := append(append([]string{}, path...), fmt.Sprintf("%v", item.Key))
+		switch v := item.Value.(type) {
+		case yaml.MapSlice:
+			collectLeaves(v, p, leaves)
+		case []interface{}:
+			for _, e := range v {
+				leaves[strings.Join(p, ":")] = e
+			}
+		default:
+			leaves[strings.Join(p, ":")] = v
+		}
+	}
+}
+
+// swapData swaps the encrypted values of data.ca and data.key, which only
+// decrypt with the path they were encrypted for.
+func swapData(t *testing.T, doc []byte) []byte {
+	t.Helper()
+
+	tree := unmarshal(t, doc)
+	for _, item := range tree {
+		if item.Key != "data" {
+			continue
+		}
+		data := item.Value.(yaml.MapSlice)
+		data[0].Value, data[1].Value = data[1].Value, data[0].Value
+	}
+
+	b, err := yaml.Marshal(tree)
+	if err != nil {
+		t.Fatalf("yaml.Marshal() error = %v", err)
+	}
+
+	return b
+}
+
+var sopsValue = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.*),iv:(.+),tag:(.+),type:(.+)\]$`)
+
+func openValue(t *testing.T, s string, key []byte, additionalData string) (string, string) {
+	t.Helper()
+
+	m := sopsValue.FindStringSubmatch(s)
+	if m == nil {
+		t.Fatalf("%q is not a sops value", s)
+	}
+
+	var parts [][]byte
+	for _, p := range m[1:4] {
+		b, err := base64.StdEncoding.DecodeString(p)
+		if err != nil {
+			t.Fatalf("base64 decoding %q error = %v", p, err)
+		}
+		parts = append(parts, b)
+	}
+	if len(parts[1]) != 32 || len(parts[2]) != 16 {
+		t.Fatalf("iv has %d bytes and tag %d bytes, want 32 and 16", len(parts[1]), len(parts[2]))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatalf("aes.NewCipher() error = %v", err)
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, 32)
+	if err != nil {
+		t.Fatalf("cipher.NewGCMWithNonceSize() error = %v", err)
+	}
+	plaintext, err := gcm.Open(nil, parts[1], append(parts[0], parts[2]...), []byte(additionalData))
+	if err != nil {
+		t.Fatalf("opening value with additional data %q error = %v", additionalData, err)
+	}
+
+	return string(plaintext), m[4]
+}
@@ -0,0 +1,198 @@
+// Package state records the progress of a cluster migration in a state file,
+// so that a failed migration can be resumed from the last successful phase.
+package state
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	PhaseControlPlane      = "control-plane"
+	PhaseControlPlaneScale = "control-plane-scale"
+	PhaseDNS               = "dns"
+	PhaseNodePools         = "node-pools"
+)
+
+// Phases are all migration phases in the order in which they are executed.
+var Phases = []string{
+	PhaseControlPlane,
+	PhaseControlPlaneScale,
+	PhaseDNS,
+	PhaseNodePools,
+}
+
+type Migration struct {
+	ClusterID string `json:"clusterID"`
+	Context   string `json:"context"`
+
+	// AWSRegion is the flag the migration was started with and K8sVersion
+	// the Kubernetes version of the new cluster, a resumed migration
+	// continues with the same values.
+	AWSRegion  string `json:"awsRegion,omitempty"`
+	K8sVersion string `json:"k8sVersion,omitempty"`
+
+	// OriginalDNSTarget is the target of the API DNS record before it was
+	// switched to the ELB of the new control plane.
+	OriginalDNSTarget string `json:"originalDNSTarget,omitempty"`
+
+	Phases      []Phase  `json:"phases,omitempty"`
+	LastFailure *Failure `json:"lastFailure,omitempty"`
+
+	StartedAt time.Time `json:"startedAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type Phase struct {
+	Name        string      `json:"name"`
+	CompletedAt time.Time   `json:"completedAt"`
+	Objects     []ObjectRef `json:"objects,omitempty"`
+}
+
+type ObjectRef struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+type Failure struct {
+	Phase    string    `json:"phase"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failedAt"`
+}
+
+// DefaultPath returns the state file used when no path is given.
+func DefaultPath(clusterID string) string {
+	return fmt.Sprintf("%s.migration.yaml", clusterID)
+}
+
+// Load reads the migration state from path. A new migration is returned if
+// the file does not exist yet.
+func Load(path string, clusterID string) (*Migration, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		m := &Migration{
+			ClusterID: clusterID,
+			StartedAt: time.Now().UTC(),
+		}
+		return m, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var m Migration
+	err = yaml.UnmarshalStrict(b, &m)
+	if err != nil {
+		return nil, microerror.Maskf(invalidStateError, "state file %s is corrupt: %s", path, err)
+	}
+
+	if m.ClusterID != clusterID {
+		return nil, microerror.Maskf(invalidStateError, "state file %s belongs to cluster %q and not to %q", path, m.ClusterID, clusterID)
+	}
+
+	return &m, nil
+}
+
+// Exists returns true if a state file exists at path.
+func Exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Save writes the migration state to path.
+func (m *Migration) Save(path string) error {
+	m.UpdatedAt = time.Now().UTC()
+
+	b, err := yaml.Marshal(m)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = ioutil.WriteFile(path, b, 0600)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// Completed returns true if the phase has been completed.
+func (m *Migration) Completed(phase string) bool {
+	for _, p := range m.Phases {
+		if p.Name == phase {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Complete records the phase as completed together with the objects it
+// created and clears the last failure.
+func (m *Migration) Complete(phase string, objs []runtime.Object) error {
+	p := Phase{
+		Name:        phase,
+		CompletedAt: time.Now().UTC(),
+	}
+
+	for _, o := range objs {
+		a, err := meta.Accessor(o)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		p.Objects = append(p.Objects, ObjectRef{
+			Kind:      o.GetObjectKind().GroupVersionKind().Kind,
+			Namespace: a.GetNamespace(),
+			Name:      a.GetName(),
+		})
+	}
+
+	m.Reset(phase)
+	m.Phases = append(m.Phases, p)
+	m.LastFailure = nil
+
+	return nil
+}
+
+// Fail records the error the phase failed with.
+func (m *Migration) Fail(phase string, err error) {
+	m.LastFailure = &Failure{
+		Phase:    phase,
+		Error:    err.Error(),
+		FailedAt: time.Now().UTC(),
+	}
+}
+
+// Reset removes the phase from the completed phases, e.g. after its resources
+// have been deleted.
+func (m *Migration) Reset(phase string) {
+	var phases []Phase
+	for _, p := range m.Phases {
+		if p.Name != phase {
+			phases = append(phases, p)
+		}
+	}
+
+	m.Phases = phases
+}
+
+// Remaining returns the phases which have not been completed yet, in the
+// order in which they have to be executed.
+func (m *Migration) Remaining() []string {
+	var remaining []string
+	for _, p := range Phases {
+		if !m.Completed(p) {
+			remaining = append(remaining, p)
+		}
+	}
+
+	return remaining
+}
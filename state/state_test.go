@@ -0,0 +1,224 @@
+package state
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_Migration_Phases(t *testing.T) {
+	testCases := []struct {
+		name              string
+		completed         []string
+		reset             []string
+		expectedRemaining []string
+		expectedCompleted []string
+	}{
+		{
+			name:              "case 0: fresh migration",
+			expectedRemaining: Phases,
+		},
+		{
+			name:              "case 1: after control-plane",
+			completed:         []string{PhaseControlPlane},
+			expectedRemaining: []string{PhaseControlPlaneScale, PhaseDNS, PhaseNodePools},
+			expectedCompleted: []string{PhaseControlPlane},
+		},
+		{
+			name:              "case 2: after control-plane-scale",
+			completed:         []string{PhaseControlPlane, PhaseControlPlaneScale},
+			expectedRemaining: []string{PhaseDNS, PhaseNodePools},
+			expectedCompleted: []string{PhaseControlPlane, PhaseControlPlaneScale},
+		},
+		{
+			name:              "case 3: after dns",
+			completed:         []string{PhaseControlPlane, PhaseControlPlaneScale, PhaseDNS},
+			expectedRemaining: []string{PhaseNodePools},
+			expectedCompleted: []string{PhaseControlPlane, PhaseControlPlaneScale, PhaseDNS},
+		},
+		{
+			name:              "case 4: after node-pools",
+			completed:         Phases,
+			expectedCompleted: Phases,
+		},
+		{
+			name:              "case 5: phase completed again is recorded once",
+			completed:         []string{PhaseControlPlane, PhaseControlPlaneScale, PhaseControlPlane},
+			expectedRemaining: []string{PhaseDNS, PhaseNodePools},
+			expectedCompleted: []string{PhaseControlPlaneScale, PhaseControlPlane},
+		},
+		{
+			name:              "case 6: phases completed out of order are remaining in order",
+			completed:         []string{PhaseDNS},
+			expectedRemaining: []string{PhaseControlPlane, PhaseControlPlaneScale, PhaseNodePools},
+			expectedCompleted: []string{PhaseDNS},
+		},
+		{
+			name:              "case 7: control plane deleted after node-pools",
+			completed:         Phases,
+			reset:             []string{PhaseControlPlane, PhaseControlPlaneScale},
+			expectedRemaining: []string{PhaseControlPlane, PhaseControlPlaneScale},
+			expectedCompleted: []string{PhaseDNS, PhaseNodePools},
+		},
+		{
+			name:              "case 8: node pools deleted after node-pools",
+			completed:         Phases,
+			reset:             []string{PhaseNodePools},
+			expectedRemaining: []string{PhaseNodePools},
+			expectedCompleted: []string{PhaseControlPlane, PhaseControlPlaneScale, PhaseDNS},
+		},
+		{
+			name:              "case 9: reset of a phase which is not completed",
+			completed:         []string{PhaseControlPlane},
+			reset:             []string{PhaseDNS},
+			expectedRemaining: []string{PhaseControlPlaneScale, PhaseDNS, PhaseNodePools},
+			expectedCompleted: []string{PhaseControlPlane},
+		},
+		{
+			name:              "case 10: everything deleted",
+			completed:         Phases,
+			reset:             Phases,
+			expectedRemaining: Phases,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := &Migration{ClusterID: "abc12"}
+			m.Fail(PhaseControlPlane, invalidStateError)
+
+			for _, p := range tc.completed {
+				err := m.Complete(p, nil)
+				if err != nil {
+					t.Fatalf("Complete() error = %v", err)
+				}
+			}
+			for _, p := range tc.reset {
+				m.Reset(p)
+			}
+
+			if remaining := m.Remaining(); !reflect.DeepEqual(remaining, tc.expectedRemaining) {
+				t.Errorf("Remaining() = %v, want %v", remaining, tc.expectedRemaining)
+			}
+
+			var completed []string
+			for _, p := range m.Phases {
+				completed = append(completed, p.Name)
+			}
+			if !reflect.DeepEqual(completed, tc.expectedCompleted) {
+				t.Errorf("completed phases = %v, want %v", completed, tc.expectedCompleted)
+			}
+			for _, p := range Phases {
+				if m.Completed(p) != contains(tc.expectedCompleted, p) {
+					t.Errorf("Completed(%q) = %t", p, m.Completed(p))
+				}
+			}
+
+			// A completed phase clears the last failure.
+			if failed := m.LastFailure != nil; failed != (len(tc.completed) == 0) {
+				t.Errorf("LastFailure = %v", m.LastFailure)
+			}
+		})
+	}
+}
+
+func Test_Load(t *testing.T) {
+	testCases := []struct {
+		name              string
+		content           string
+		expectedRemaining []string
+		match             func(error) bool
+	}{
+		{
+			name:              "case 0: missing state file",
+			expectedRemaining: Phases,
+		},
+		{
+			name:              "case 1: saved state file",
+			content:           "clusterID: abc12\nphases:\n- name: control-plane\n  completedAt: \"2021-05-01T10:00:00Z\"\n",
+			expectedRemaining: []string{PhaseControlPlaneScale, PhaseDNS, PhaseNodePools},
+		},
+		{
+			name:    "case 2: state file of another cluster",
+			content: "clusterID: xyz89\n",
+			match:   IsInvalidState,
+		},
+		{
+			name:    "case 3: corrupt state file",
+			content: "clusterID: abc12\nphases: [\n",
+			match:   IsInvalidState,
+		},
+		{
+			name:    "case 4: state file with unknown fields",
+			content: "clusterID: abc12\nphase: control-plane\n",
+			match:   IsInvalidState,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), DefaultPath("abc12"))
+			if tc.content != "" {
+				err := ioutil.WriteFile(path, []byte(tc.content), 0600)
+				if err != nil {
+					t.Fatalf("ioutil.WriteFile() error = %v", err)
+				}
+			}
+
+			m, err := Load(path, "abc12")
+			switch {
+			case tc.match == nil && err != nil:
+				t.Fatalf("Load() error = %v", err)
+			case tc.match != nil && !tc.match(err):
+				t.Fatalf("Load() error = %v, want a matching error", err)
+			case tc.match == nil && !reflect.DeepEqual(m.Remaining(), tc.expectedRemaining):
+				t.Fatalf("Remaining() = %v, want %v", m.Remaining(), tc.expectedRemaining)
+			}
+		})
+	}
+}
+
+func Test_Migration_Save(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultPath("abc12"))
+
+	m, err := Load(path, "abc12")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if Exists(path) {
+		t.Fatalf("Exists() = true before Save()")
+	}
+
+	m.AWSRegion = "eu-central-1"
+	m.OriginalDNSTarget = "old-elb"
+	err = m.Complete(PhaseControlPlane, nil)
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	err = m.Save(path)
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := Load(path, "abc12")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if loaded.AWSRegion != m.AWSRegion || loaded.OriginalDNSTarget != m.OriginalDNSTarget {
+		t.Fatalf("Load() = %+v, want %+v", loaded, m)
+	}
+	if !reflect.DeepEqual(loaded.Remaining(), []string{PhaseControlPlaneScale, PhaseDNS, PhaseNodePools}) {
+		t.Fatalf("Remaining() = %v", loaded.Remaining())
+	}
+}
+
+func contains(l []string, s string) bool {
+	for _, e := range l {
+		if e == s {
+			return true
+		}
+	}
+
+	return false
+}